@@ -0,0 +1,259 @@
+// Package audit writes audit events which must never be sampled, dropped
+// or reordered
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fun-think/gologger"
+	"github.com/fun-think/gologger/format"
+)
+
+// These are common event outcomes
+const (
+	Success = "success"
+	Failure = "failure"
+	Denied  = "denied"
+)
+
+var (
+	// ErrOff is returned when setting the level to OFF
+	ErrOff = errors.New("audit: level can not be OFF")
+
+	// ErrMissingField is returned for events without a required field
+	ErrMissingField = errors.New("audit: missing required field")
+
+	// ErrNoOutput is returned when Output is nil
+	ErrNoOutput = errors.New("audit: no output")
+
+	// ErrFormat is returned when Format failed to format an event
+	ErrFormat = errors.New("audit: event could not be formatted")
+)
+
+// Event is an audited action, Actor, Action, Resource and Outcome are
+// required and written as fields of the same name
+type Event struct {
+	Actor    string // who, e.g. a user or service id
+	Action   string // what, e.g. "user.delete"
+	Resource string // on what, e.g. "user/42"
+	Outcome  string // e.g. Success, Failure or Denied
+
+	Message string          // default is Action
+	Fields  gologger.Fields // extra fields
+}
+
+// validate returns an error for the first empty required field
+func (e *Event) validate() error {
+	for _, f := range [...]struct{ name, value string }{
+		{"actor", e.Actor},
+		{"action", e.Action},
+		{"resource", e.Resource},
+		{"outcome", e.Outcome},
+	} {
+		if f.value == "" {
+			return fmt.Errorf("%w %s", ErrMissingField, f.name)
+		}
+	}
+	return nil
+}
+
+// Logger is the audit logger. Unlike gologger.Logger it writes every event
+// regardless of level, in order, and Log returns only after the event was
+// written and synced to stable storage, or with the error which prevented
+// it instead of reporting to stderr.
+type Logger struct {
+	Format gologger.Format // default is format.JSONFormat
+	Output io.Writer
+
+	// GroupCommit delays syncing Output to commit the events logged
+	// concurrently within the window together, 0 syncs every event.
+	// Log returns once its event was synced either way, the file writers
+	// of the writer package sync a file when closing it at rotation and
+	// report a failure with the next Sync
+	GroupCommit time.Duration
+
+	level  atomic.Uint32
+	logger *gologger.Logger
+	init   sync.Once
+
+	mutex    sync.Mutex // serializes writes
+	written  uint64     // events written
+	writeErr error      // set by the ErrorHandler of logger during a write
+
+	syncMutex sync.Mutex
+	synced    uint64        // events synced
+	failed    []failedRange // of events which failed to sync
+}
+
+// failedRange is the events from < seq <= to which failed to sync with err
+type failedRange struct {
+	from, to uint64
+	err      error
+}
+
+// New returns a Logger writing JSON to output
+func New(output io.Writer) *Logger {
+	return &Logger{Format: new(format.JSONFormat), Output: output}
+}
+
+// Level returns the level events are written at, default is INFO
+func (l *Logger) Level() gologger.Level {
+	if level := gologger.Level(l.level.Load()); level != gologger.OFF {
+		return level
+	}
+	return gologger.INFO
+}
+
+// SetLevel sets the level events are written at, it refuses OFF
+func (l *Logger) SetLevel(level gologger.Level) error {
+	if level == gologger.OFF || level > gologger.DEBUG {
+		return ErrOff
+	}
+	l.level.Store(uint32(level))
+	return nil
+}
+
+// Log writes e and syncs Output, it returns an error if e is invalid or
+// could not be written or synced
+func (l *Logger) Log(e Event) error {
+	if err := e.validate(); err != nil {
+		return err
+	}
+	if l.Output == nil {
+		return ErrNoOutput
+	}
+
+	l.init.Do(func() {
+		if l.Format == nil {
+			l.Format = new(format.JSONFormat)
+		}
+		l.logger = &gologger.Logger{
+			Level:      gologger.DEBUG,
+			Format:     l.Format,
+			Output:     output{l.Output},
+			CallerSkip: 1,
+			ErrorHandler: func(err error) {
+				var werr *gologger.WriteError
+				if errors.As(err, &werr) {
+					werr.Output = l.Output
+				}
+				l.writeErr = errors.Join(l.writeErr, err)
+			},
+		}
+	})
+
+	fields := make(gologger.Fields, len(e.Fields)+4)
+	for k, v := range e.Fields {
+		fields[k] = v
+	}
+	fields["actor"] = e.Actor
+	fields["action"] = e.Action
+	fields["resource"] = e.Resource
+	fields["outcome"] = e.Outcome
+	msg := e.Message
+	if msg == "" {
+		msg = e.Action
+	}
+	logger := l.logger.WithFields(fields)
+
+	l.mutex.Lock()
+	l.writeErr = nil
+	logger.Log(l.Level(), msg)
+	err := l.writeErr
+	if err == nil {
+		l.written++
+	}
+	seq := l.written
+	l.mutex.Unlock()
+
+	if err != nil {
+		return err
+	}
+	return l.sync(seq)
+}
+
+// Sync commits all written events to stable storage
+func (l *Logger) Sync() error {
+	l.mutex.Lock()
+	seq := l.written
+	l.mutex.Unlock()
+	return l.sync(seq)
+}
+
+// sync syncs Output unless event seq was synced by another call
+func (l *Logger) sync(seq uint64) error {
+	l.syncMutex.Lock()
+	defer l.syncMutex.Unlock()
+
+	// a failed sync may have lost the event, even if a later one succeeds
+	for _, r := range l.failed {
+		if seq > r.from && seq <= r.to {
+			return r.err
+		}
+	}
+	if l.synced >= seq {
+		return nil
+	}
+	if l.GroupCommit > 0 {
+		time.Sleep(l.GroupCommit)
+	}
+
+	// writes are blocked, as file writers may switch files while writing
+	l.mutex.Lock()
+	written := l.written
+	err := syncOutput(l.Output)
+	l.mutex.Unlock()
+
+	if err != nil {
+		if n := len(l.failed); n > 0 && l.failed[n-1].from == l.synced {
+			l.failed[n-1].to = written
+		} else {
+			l.failed = append(l.failed, failedRange{l.synced, written, err})
+		}
+		return err
+	}
+	l.synced = written
+	return nil
+}
+
+// syncOutput syncs output if it supports it, like *os.File and the file
+// writers of the writer package. Pipes and terminals can not be synced
+func syncOutput(output io.Writer) error {
+	s, ok := output.(interface{ Sync() error })
+	if !ok {
+		return nil
+	}
+	if err := s.Sync(); err != nil && !errors.Is(err, syscall.EINVAL) && !errors.Is(err, syscall.ENOTSUP) {
+		return fmt.Errorf("audit: sync failed, %w", err)
+	}
+	return nil
+}
+
+// output is the Output of the underlying logger, it fails the empty lines
+// formats return when an event could not be formatted
+type output struct {
+	w io.Writer
+}
+
+func (o output) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, ErrFormat
+	}
+	return o.w.Write(p)
+}
+
+func (o output) WriteEntry(entry *gologger.Entry, p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, ErrFormat
+	}
+	if w, ok := o.w.(gologger.EntryWriter); ok {
+		return w.WriteEntry(entry, p)
+	}
+	return o.w.Write(p)
+}
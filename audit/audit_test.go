@@ -0,0 +1,155 @@
+package audit_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fun-think/gologger"
+	"github.com/fun-think/gologger/audit"
+	"github.com/fun-think/gologger/writer"
+)
+
+func TestLogger(t *testing.T) {
+	dir := t.TempDir()
+	logger := audit.New(&writer.SizeFileWriter{Name: filepath.Join(dir, "audit"), MaxSize: 1 << 20, MaxCount: 2})
+
+	if err := logger.SetLevel(gologger.OFF); !errors.Is(err, audit.ErrOff) {
+		t.Errorf("SetLevel(OFF) = %v", err)
+	}
+	if err := logger.Log(audit.Event{Actor: "alice", Action: "user.delete"}); !errors.Is(err, audit.ErrMissingField) ||
+		err.Error() != "audit: missing required field resource" {
+		t.Errorf("missing field: %v", err)
+	}
+
+	err := logger.Log(audit.Event{
+		Actor:    "alice",
+		Action:   "user.delete",
+		Resource: "user/42",
+		Outcome:  audit.Success,
+		Fields:   gologger.Fields{"ip": "10.0.0.1", "actor": "mallory"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "audit"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var line map[string]any
+	if err := json.Unmarshal(data, &line); err != nil {
+		t.Fatalf("%v: %s", err, data)
+	}
+	for k, want := range map[string]any{
+		"level": "INFO", "msg": "user.delete", "actor": "alice", "action": "user.delete",
+		"resource": "user/42", "outcome": "success", "ip": "10.0.0.1",
+	} {
+		if line[k] != want {
+			t.Errorf("%s = %v, want %v", k, line[k], want)
+		}
+	}
+	if file, _ := line["file"].(string); !strings.HasSuffix(file, "audit_test.go") {
+		t.Errorf("caller %v", line["file"])
+	}
+}
+
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestLoggerWriteError(t *testing.T) {
+	logger := audit.New(failWriter{})
+	err := logger.Log(audit.Event{Actor: "a", Action: "b", Resource: "c", Outcome: audit.Failure})
+	var werr *gologger.WriteError
+	if !errors.As(err, &werr) || werr.Err.Error() != "disk full" {
+		t.Errorf("got %v", err)
+	}
+}
+
+// syncWriter counts syncs
+type syncWriter struct {
+	lines atomic.Int64
+	syncs atomic.Int64
+	fail  atomic.Bool
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.lines.Add(1)
+	return len(p), nil
+}
+
+func (w *syncWriter) Sync() error {
+	w.syncs.Add(1)
+	if w.fail.Load() {
+		return errors.New("I/O error")
+	}
+	return nil
+}
+
+func TestLoggerGroupCommit(t *testing.T) {
+	w := new(syncWriter)
+	logger := &audit.Logger{Output: w, GroupCommit: 20 * time.Millisecond}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := logger.Log(audit.Event{Actor: "a", Action: "b", Resource: "c", Outcome: audit.Success}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if w.lines.Load() != 20 {
+		t.Errorf("%d lines", w.lines.Load())
+	}
+	if n := w.syncs.Load(); n < 1 || n >= 20 {
+		t.Errorf("%d syncs for 20 events", n)
+	}
+}
+
+type failFormat struct{}
+
+func (failFormat) Format(*gologger.Entry) []byte {
+	return nil
+}
+
+func TestLoggerFormatError(t *testing.T) {
+	w := new(syncWriter)
+	logger := &audit.Logger{Format: failFormat{}, Output: w}
+	err := logger.Log(audit.Event{Actor: "a", Action: "b", Resource: "c", Outcome: audit.Success})
+	if !errors.Is(err, audit.ErrFormat) || w.lines.Load() != 0 {
+		t.Errorf("got %v, %d lines", err, w.lines.Load())
+	}
+}
+
+func TestLoggerSyncError(t *testing.T) {
+	w := new(syncWriter)
+	logger := audit.New(w)
+	event := audit.Event{Actor: "a", Action: "b", Resource: "c", Outcome: audit.Success}
+
+	w.fail.Store(true)
+	if err := logger.Log(event); err == nil {
+		t.Error("failed sync reported as success")
+	}
+
+	// the event may be lost even when syncing succeeds again
+	w.fail.Store(false)
+	if err := logger.Sync(); err == nil {
+		t.Error("Sync succeeded after losing an event")
+	}
+	if err := logger.Log(event); err != nil {
+		t.Error(err)
+	}
+}
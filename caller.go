@@ -0,0 +1,115 @@
+package gologger
+
+import (
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// pkgPrefix matches function names declared in this package
+var pkgPrefix = reflect.TypeOf((*Logger)(nil)).Elem().PkgPath() + "."
+
+// Caller is the source location of a log call
+type Caller struct {
+	PC       uintptr
+	File     string
+	Line     int
+	Function string
+}
+
+// Defined indicates whether the caller was resolved
+func (c Caller) Defined() bool {
+	return c.PC != 0
+}
+
+// ShortFile returns file as pkg/file.go
+func (c Caller) ShortFile() string {
+	n := 0
+	for i := len(c.File) - 1; i > 0; i-- {
+		if c.File[i] == '/' {
+			n++
+			if n >= 2 {
+				return c.File[i+1:]
+			}
+		}
+	}
+	return c.File
+}
+
+// ShortFunction returns function name without import path, e.g. pkg.(*T).Method
+func (c Caller) ShortFunction() string {
+	if i := strings.LastIndexByte(c.Function, '/'); i >= 0 {
+		return c.Function[i+1:]
+	}
+	return c.Function
+}
+
+// String returns pkg/file.go:line
+func (c Caller) String() string {
+	if !c.Defined() {
+		return "???:0"
+	}
+	return c.ShortFile() + ":" + strconv.Itoa(c.Line)
+}
+
+// stdlogPrefix matches function names of the standard log package, which
+// logs through gologger with StdLogger and RedirectStdLog
+const stdlogPrefix = "log."
+
+// isLoggerFrame reports whether function belongs to gologger itself or
+// to a package logging through it
+func (l *Logger) isLoggerFrame(function string) bool {
+	return strings.HasPrefix(function, pkgPrefix) || strings.HasPrefix(function, stdlogPrefix)
+}
+
+// Stack is a list of frames from the log site outwards
+type Stack []Caller
+
+// String returns stack formatted like a goroutine trace
+func (s Stack) String() string {
+	var sb strings.Builder
+	for _, c := range s {
+		sb.WriteString(c.Function)
+		sb.WriteString("\n\t")
+		sb.WriteString(c.File)
+		sb.WriteByte(':')
+		sb.WriteString(strconv.Itoa(c.Line))
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// capture returns the first frame outside of gologger, skipping CallerSkip
+// more frames, and the stack from that frame if withStack
+func (l *Logger) capture(withStack bool) (caller Caller, stack Stack) {
+	var pcs [64]uintptr
+	// skip runtime.Callers and capture itself
+	n := runtime.Callers(2, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+
+	skip := l.CallerSkip
+	for {
+		frame, more := frames.Next()
+		if caller.Defined() || (!l.isLoggerFrame(frame.Function) && skip <= 0) {
+			c := Caller{
+				PC:       frame.PC,
+				File:     frame.File,
+				Line:     frame.Line,
+				Function: frame.Function,
+			}
+			if !caller.Defined() {
+				caller = c
+			}
+			if !withStack {
+				return caller, nil
+			}
+			stack = append(stack, c)
+		} else if !l.isLoggerFrame(frame.Function) {
+			skip--
+		}
+		if !more {
+			return caller, stack
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package gologger_test
+
+import (
+	"io"
+	"log"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/fun-think/gologger"
+)
+
+type callerFormat struct {
+	caller  gologger.Caller
+	stack   gologger.Stack
+	message string
+	level   gologger.Level
+}
+
+func (f *callerFormat) Format(entry *gologger.Entry) []byte {
+	f.caller = entry.Caller
+	f.stack = entry.Stack
+	f.message = entry.Message
+	f.level = entry.Level
+	return nil
+}
+
+func currentLine() int {
+	_, _, line, _ := runtime.Caller(1)
+	return line
+}
+
+func logWrapper(l *gologger.Logger, msg string) {
+	l.Info(msg)
+}
+
+func TestCaller(t *testing.T) {
+	format := new(callerFormat)
+	logger := &gologger.Logger{Level: gologger.DEBUG, Format: format, Output: io.Discard}
+
+	check := func(name string, line int) {
+		t.Helper()
+		c := format.caller
+		if filepath.Base(c.File) != "caller_test.go" || c.Line != line {
+			t.Errorf("%s: got %s, want caller_test.go:%d", name, c, line)
+		}
+		if !strings.HasSuffix(c.Function, "TestCaller") {
+			t.Errorf("%s: got function %q", name, c.Function)
+		}
+	}
+
+	logger.Info("method")
+	check("method", currentLine()-1)
+
+	saved := gologger.Default
+	gologger.Default = logger
+	gologger.Infof("package %s", "func")
+	check("package func", currentLine()-1)
+	gologger.Default = saved
+
+	logWrapper(logger.AddCallerSkip(1), "wrapped")
+	check("wrapped", currentLine()-1)
+}
+
+func TestStacktrace(t *testing.T) {
+	format := new(callerFormat)
+	logger := &gologger.Logger{Level: gologger.DEBUG, Format: format, Output: io.Discard, StacktraceLevel: gologger.ERROR}
+
+	logger.Warn("warn")
+	if len(format.stack) != 0 {
+		t.Errorf("unexpected stack for WARN: %v", format.stack)
+	}
+
+	logger.Error("error")
+	if len(format.stack) == 0 || format.stack[0] != format.caller {
+		t.Fatalf("stack should start at caller, got %v", format.stack)
+	}
+
+	logger.StacktraceLevel = gologger.OFF
+	func() {
+		defer func() { recover() }()
+		logger.Panic("panic")
+	}()
+	if len(format.stack) == 0 {
+		t.Errorf("PANIC should always have a stack")
+	}
+}
+
+func TestStdLogger(t *testing.T) {
+	format := new(callerFormat)
+	logger := &gologger.Logger{Level: gologger.DEBUG, Format: format, Output: io.Discard}
+
+	std := logger.StdLogger(gologger.WARN)
+	std.Printf("from %s", "stdlib")
+	line := currentLine() - 1
+	if format.message != "from stdlib" || format.level != gologger.WARN {
+		t.Errorf("got %v %q", format.level, format.message)
+	}
+	if filepath.Base(format.caller.File) != "caller_test.go" || format.caller.Line != line {
+		t.Errorf("got caller %s, want caller_test.go:%d", format.caller, line)
+	}
+
+	flags, prefix, output := log.Flags(), log.Prefix(), log.Writer()
+	defer func() {
+		log.SetFlags(flags)
+		log.SetPrefix(prefix)
+		log.SetOutput(output)
+	}()
+
+	log.SetPrefix("[lib] ")
+	gologger.RedirectStdLog(logger)
+	log.Println("redirected")
+	if format.message != "redirected" || format.level != gologger.INFO {
+		t.Errorf("got %v %q", format.level, format.message)
+	}
+
+	// changes after the call apply
+	logger.Level = gologger.ERROR
+	std.Print("dropped")
+	if format.message != "redirected" {
+		t.Errorf("got %q above level", format.message)
+	}
+	logger.Level = gologger.DEBUG
+	for _, msg := range []string{"2024/01/02 backup done", "12:00:00 meeting", "main.go:1: text"} {
+		log.Print(msg)
+		if format.message != msg {
+			t.Errorf("got %q, want %q", format.message, msg)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/fun-think/gologger/writer"
+)
+
+func decrypt(args []string) error {
+	fs := flag.NewFlagSet("decrypt", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: gologger decrypt -keys file [file|dir ...]\n\n"+
+			"Decrypts files written by writer.EncryptWriter to stdout, rotated files in\n"+
+			"chronological order, or stdin without files. The keys file has a line\n"+
+			"\"id hexkey\" for every key id.\n\n")
+		fs.PrintDefaults()
+	}
+	keysFile := fs.String("keys", "", "read keys from `file`")
+	fs.Parse(args)
+
+	keys, err := readKeys(*keysFile)
+	if err != nil {
+		return err
+	}
+	key := func(id string) ([]byte, error) {
+		if k, ok := keys[id]; ok {
+			return k, nil
+		}
+		return nil, fmt.Errorf("no key %q in %s", id, *keysFile)
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+
+	if fs.NArg() == 0 {
+		return decryptTo(out, "stdin", os.Stdin, key)
+	}
+	files, err := logFiles(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	// the other files are still decrypted when chunks of one were lost
+	lost := 0
+	for _, name := range files {
+		r, err := openLog(name)
+		if err != nil {
+			return err
+		}
+		err = decryptTo(out, name, r, key)
+		r.Close()
+		if errors.Is(err, writer.ErrTruncatedChunk) {
+			fmt.Fprintf(os.Stderr, "gologger: %v\n", err)
+			lost++
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if lost > 0 {
+		return fmt.Errorf("the last chunk of %d files was lost", lost)
+	}
+	return nil
+}
+
+// decryptTo writes the content of r to w, the error wraps ErrTruncatedChunk
+// when the last chunk of r was lost
+func decryptTo(w io.Writer, name string, r io.Reader, key func(id string) ([]byte, error)) error {
+	if _, err := io.Copy(w, &writer.DecryptReader{Reader: r, Key: key}); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// readKeys reads "id hexkey" lines of name
+func readKeys(name string) (map[string][]byte, error) {
+	if name == "" {
+		return nil, errors.New("no keys file, use -keys")
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[string][]byte{}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		id, value, ok := strings.Cut(line, " ")
+		key, err := hex.DecodeString(strings.TrimSpace(value))
+		if !ok || err != nil {
+			return nil, fmt.Errorf("%s:%d: expected \"id hexkey\"", name, i+1)
+		}
+		keys[id] = key
+	}
+	return keys, nil
+}
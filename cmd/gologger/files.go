@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// isLogFile indicates whether name looks like a file written by the writer package
+func isLogFile(name string) bool {
+	return strings.HasSuffix(name, ".log") || strings.HasSuffix(name, ".log.gz")
+}
+
+// logFiles expands args into log files in chronological order. A directory
+// expands to its log files (DailyFileWriter), any other arg to itself and
+// its rotated siblings arg.* (SizeFileWriter, NewFileWriter)
+func logFiles(args []string) ([]string, error) {
+	seen := map[string]bool{}
+	var files []string
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			files = append(files, name)
+		}
+	}
+
+	for _, arg := range args {
+		stat, err := os.Lstat(arg)
+		if err == nil && stat.IsDir() {
+			entries, err := os.ReadDir(arg)
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range entries {
+				if e.Type().IsRegular() && isLogFile(e.Name()) {
+					add(filepath.Join(arg, e.Name()))
+				}
+			}
+			continue
+		}
+
+		// a symlink is the current file link of a writer, its target is a sibling
+		if err == nil && stat.Mode().IsRegular() {
+			add(arg)
+		}
+		matches, _ := filepath.Glob(arg + ".*")
+		for _, m := range matches {
+			if isLogFile(m) {
+				add(m)
+			}
+		}
+		if err != nil && len(matches) == 0 {
+			return nil, err
+		}
+	}
+
+	sortByModTime(files)
+	return files, nil
+}
+
+// sortByModTime sorts files from oldest to newest, by name if equal
+func sortByModTime(files []string) {
+	mtime := make(map[string]int64, len(files))
+	for _, f := range files {
+		if stat, err := os.Stat(f); err == nil {
+			mtime[f] = stat.ModTime().UnixNano()
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		if mtime[files[i]] != mtime[files[j]] {
+			return mtime[files[i]] < mtime[files[j]]
+		}
+		return files[i] < files[j]
+	})
+}
+
+// openLog opens name, decompressing gzipped files
+func openLog(name string) (io.ReadCloser, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(name, ".gz") {
+		return file, nil
+	}
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{zr, file}, nil
+}
+
+// recordReader joins indented stack lines to the record before them
+type recordReader struct {
+	parser  *parser
+	pending *record
+}
+
+// feed parses line and returns the previous record once complete
+func (rr *recordReader) feed(line string) *record {
+	if strings.HasPrefix(line, "\t") && rr.pending != nil {
+		rr.pending.Stack += strings.TrimPrefix(line, "\t") + "\n"
+		return nil
+	}
+	r := rr.pending
+	rr.pending = rr.parser.parse(line)
+	return r
+}
+
+// flush returns the pending record
+func (rr *recordReader) flush() *record {
+	r := rr.pending
+	rr.pending = nil
+	return r
+}
+
+// readRecords calls emit for every record of r
+func readRecords(r io.Reader, p *parser, emit func(*record) error) error {
+	rr := &recordReader{parser: p}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if rec := rr.feed(scanner.Text()); rec != nil {
+			if err := emit(rec); err != nil {
+				return err
+			}
+		}
+	}
+	if rec := rr.flush(); rec != nil {
+		if err := emit(rec); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// follower reads a growing log file like tail -f, switching files when
+// the writer rotates, reopens or truncates it
+type follower struct {
+	args     []string // args of logFiles, resolved again to find new files
+	parser   *parser
+	interval time.Duration
+}
+
+// follow reads name from offset and keeps reading new lines until an error
+func (f *follower) follow(name string, offset int64, emit func(*record) error) error {
+	rr := &recordReader{parser: f.parser}
+	for {
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		if _, err := file.Seek(offset, io.SeekStart); err != nil {
+			file.Close()
+			return err
+		}
+
+		next, err := f.tail(file, name, rr, emit)
+		file.Close()
+		if err != nil {
+			return err
+		}
+		name, offset = next, 0
+	}
+}
+
+// tail reads file until it is replaced, returning the name to read next
+func (f *follower) tail(file *os.File, name string, rr *recordReader, emit func(*record) error) (string, error) {
+	reader := bufio.NewReader(file)
+	var partial strings.Builder
+	for {
+		line, err := reader.ReadString('\n')
+		partial.WriteString(line)
+		if err == nil {
+			if rec := rr.feed(strings.TrimSuffix(partial.String(), "\n")); rec != nil {
+				if err := emit(rec); err != nil {
+					return "", err
+				}
+			}
+			partial.Reset()
+			continue
+		}
+		if err != io.EOF {
+			return "", err
+		}
+
+		// lines of an entry are written at once, so the entry is complete
+		if partial.Len() == 0 {
+			if rec := rr.flush(); rec != nil {
+				if err := emit(rec); err != nil {
+					return "", err
+				}
+			}
+		}
+		time.Sleep(f.interval)
+
+		if next := f.rotated(file, name); next != "" {
+			// drain what was written before the switch
+			if _, err := io.Copy(&partial, reader); err == nil && partial.Len() > 0 {
+				for _, l := range strings.Split(strings.TrimSuffix(partial.String(), "\n"), "\n") {
+					if rec := rr.feed(l); rec != nil {
+						if err := emit(rec); err != nil {
+							return "", err
+						}
+					}
+				}
+			}
+			return next, nil
+		}
+	}
+}
+
+// rotated returns the file to continue with if name was replaced,
+// truncated, or a newer log file appeared, otherwise ""
+func (f *follower) rotated(file *os.File, name string) string {
+	current, err := file.Stat()
+	if err != nil {
+		return ""
+	}
+	stat, err := os.Stat(name)
+	if err == nil && !os.SameFile(current, stat) {
+		return name
+	}
+	if pos, err := file.Seek(0, io.SeekCurrent); err == nil && current.Size() < pos {
+		// truncated by copytruncate, read from start
+		file.Seek(0, io.SeekStart)
+	}
+
+	files, err := logFiles(f.args)
+	if err != nil || len(files) == 0 {
+		return ""
+	}
+	newest := files[len(files)-1]
+	if newestStat, err := os.Stat(newest); err == nil && !os.SameFile(current, newestStat) &&
+		newestStat.ModTime().After(current.ModTime()) {
+		return newest
+	}
+	return ""
+}
@@ -0,0 +1,38 @@
+// Command gologger reads and queries files written by gologger
+//
+// Usage:
+//
+//	gologger [query] [flags] [file|dir ...]
+//	gologger pretty [flags] < file
+//	gologger verify [flags] file|dir ...
+//	gologger decrypt -keys file [file|dir ...]
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// commands are subcommands by name, query is used when none is given
+var commands = map[string]func(args []string) error{
+	"query":   query,
+	"pretty":  pretty,
+	"verify":  verify,
+	"decrypt": decrypt,
+}
+
+func main() {
+	args := os.Args[1:]
+	cmd := query
+	if len(args) > 0 {
+		if c, ok := commands[args[0]]; ok {
+			cmd = c
+			args = args[1:]
+		}
+	}
+
+	if err := cmd(args); err != nil {
+		fmt.Fprintf(os.Stderr, "gologger: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// renderText writes r in the TextFormat layout
+func renderText(w io.Writer, r *record, timeFormat string) {
+	if !r.parsed() {
+		fmt.Fprintln(w, r.Raw)
+		return
+	}
+	caller := r.caller()
+	if r.Func != "" {
+		caller += "(" + r.Func + ")"
+	}
+	fmt.Fprintf(w, "%s %s %s %s %d %s %s", r.Time.Format(timeFormat), r.Level, r.Host, r.App, r.PID, caller, r.Msg)
+	for _, k := range sortedFields(r.Fields) {
+		fmt.Fprintf(w, " %s=%s", k, logfmtValue(r.Fields[k]))
+	}
+	fmt.Fprintln(w)
+	for _, line := range strings.Split(strings.TrimSuffix(r.Stack, "\n"), "\n") {
+		if line != "" {
+			fmt.Fprintf(w, "\t%s\n", line)
+		}
+	}
+}
+
+// renderJSON writes r in the JSONFormat layout
+func renderJSON(w io.Writer, r *record, timeFormat string) {
+	data := make(map[string]any, 10+len(r.Fields))
+	for k, v := range r.Fields {
+		data[k] = v
+	}
+	if r.parsed() {
+		data["time"] = r.Time.Format(timeFormat)
+		data["level"] = r.Level.String()
+		data["host"] = r.Host
+		data["app"] = r.App
+		data["pid"] = r.PID
+		data["file"] = r.File
+		data["line"] = r.Line
+		if r.Func != "" {
+			data["func"] = r.Func
+		}
+		if r.Stack != "" {
+			data["stacktrace"] = r.Stack
+		}
+	}
+	data["msg"] = r.Msg
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(data)
+	w.Write(buf.Bytes())
+}
+
+// renderLogfmt writes r as key=value pairs
+func renderLogfmt(w io.Writer, r *record, timeFormat string) {
+	var sb strings.Builder
+	pair := func(k string, v any) {
+		if sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		sb.WriteString(logfmtValue(v))
+	}
+
+	if r.parsed() {
+		pair("time", r.Time.Format(timeFormat))
+		pair("level", r.Level.String())
+		pair("host", r.Host)
+		pair("app", r.App)
+		pair("pid", r.PID)
+		pair("caller", r.caller())
+		if r.Func != "" {
+			pair("func", r.Func)
+		}
+	}
+	pair("msg", r.Msg)
+	for _, k := range sortedFields(r.Fields) {
+		pair(k, r.Fields[k])
+	}
+	if r.Stack != "" {
+		pair("stacktrace", r.Stack)
+	}
+	fmt.Fprintln(w, sb.String())
+}
+
+// logfmtValue returns v quoted if needed
+func logfmtValue(v any) string {
+	var s string
+	switch v := v.(type) {
+	case string:
+		s = v
+	case map[string]any, []any:
+		b, _ := json.Marshal(v)
+		s = string(b)
+	default:
+		s = fmt.Sprint(v)
+	}
+	if s == "" || strings.ContainsAny(s, " =\"\t\r\n") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+func sortedFields(fields map[string]any) []string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
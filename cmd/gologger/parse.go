@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fun-think/gologger"
+)
+
+// record is a parsed log line of TextFormat or JSONFormat
+type record struct {
+	Time   time.Time
+	Level  gologger.Level
+	Host   string
+	App    string
+	PID    int
+	File   string
+	Line   int
+	Func   string
+	Msg    string
+	Fields map[string]any
+	Stack  string
+
+	Raw string // line as read, used when it can not be parsed
+}
+
+// parsed indicates whether the line was in a known layout
+func (r *record) parsed() bool {
+	return !r.Time.IsZero()
+}
+
+// caller returns file:line
+func (r *record) caller() string {
+	if r.File == "" {
+		return ""
+	}
+	return r.File + ":" + strconv.Itoa(r.Line)
+}
+
+// parser parses lines written with timeFormat
+type parser struct {
+	timeFormat string
+	location   *time.Location
+}
+
+func (p *parser) parse(line string) *record {
+	if strings.HasPrefix(line, "{") {
+		if r := p.parseJSON(line); r != nil {
+			return r
+		}
+	}
+	if r := p.parseText(line); r != nil {
+		return r
+	}
+	return &record{Raw: line, Msg: line}
+}
+
+// parseJSON parses the JSONFormat layout
+func (p *parser) parseJSON(line string) *record {
+	var data map[string]any
+	if json.Unmarshal([]byte(line), &data) != nil {
+		return nil
+	}
+
+	r := &record{Raw: line, Fields: map[string]any{}}
+	for k, v := range data {
+		s, _ := v.(string)
+		switch k {
+		case "time":
+			r.Time, _ = time.ParseInLocation(p.timeFormat, s, p.location)
+		case "level":
+			r.Level, _ = gologger.ParseLevel(s)
+		case "host":
+			r.Host = s
+		case "app":
+			r.App = s
+		case "pid":
+			n, _ := v.(float64)
+			r.PID = int(n)
+		case "file":
+			r.File = s
+		case "line":
+			n, _ := v.(float64)
+			r.Line = int(n)
+		case "func":
+			r.Func = s
+		case "msg":
+			r.Msg = s
+		case "stacktrace":
+			if s == "" {
+				b, _ := json.Marshal(v)
+				s = string(b)
+			}
+			r.Stack = s
+		default:
+			r.Fields[k] = v
+		}
+	}
+	if r.Time.IsZero() {
+		return nil
+	}
+	return r
+}
+
+// parseText parses the TextFormat layout:
+// TIME LEVEL HOST APP PID file:line[(func)] message
+func (p *parser) parseText(line string) *record {
+	// the time takes as many words as the time format
+	n := strings.Count(p.timeFormat, " ") + 1
+	words := strings.SplitN(line, " ", n+6)
+	if len(words) < n+5 {
+		return nil
+	}
+
+	t, err := time.ParseInLocation(p.timeFormat, strings.Join(words[:n], " "), p.location)
+	if err != nil {
+		return nil
+	}
+	level, err := gologger.ParseLevel(stripColor(words[n]))
+	if err != nil {
+		return nil
+	}
+	pid, err := strconv.Atoi(words[n+3])
+	if err != nil {
+		return nil
+	}
+
+	r := &record{
+		Raw:   line,
+		Time:  t,
+		Level: level,
+		Host:  words[n+1],
+		App:   words[n+2],
+		PID:   pid,
+	}
+
+	caller := words[n+4]
+	if i := strings.IndexByte(caller, '('); i >= 0 && strings.HasSuffix(caller, ")") {
+		r.Func = caller[i+1 : len(caller)-1]
+		caller = caller[:i]
+	}
+	if i := strings.LastIndexByte(caller, ':'); i >= 0 {
+		r.File = caller[:i]
+		r.Line, _ = strconv.Atoi(caller[i+1:])
+	}
+	if len(words) > n+5 {
+		r.Msg = words[n+5]
+	}
+	return r
+}
+
+// stripColor removes ANSI escapes of Level.ColorString
+func stripColor(s string) string {
+	for {
+		i := strings.Index(s, "\033[")
+		if i < 0 {
+			return s
+		}
+		j := strings.IndexByte(s[i:], 'm')
+		if j < 0 {
+			return s
+		}
+		s = s[:i] + s[i+j+1:]
+	}
+}
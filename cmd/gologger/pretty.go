@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/fun-think/gologger/format"
+)
+
+func pretty(args []string) error {
+	fs := flag.NewFlagSet("pretty", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: gologger pretty [flags] < file\n\n"+
+			"Renders JSONFormat lines from stdin in colored TextFormat style,\n"+
+			"other lines are passed through untouched.\n\n")
+		fs.PrintDefaults()
+	}
+	noColor := fs.Bool("no-color", false, "disable colors")
+	fs.Parse(args)
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+
+	f := &format.PrettyFormat{Color: !*noColor && isCharDevice(os.Stdout)}
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if err := f.Render(out, scanner.Bytes()); err != nil {
+			return err
+		}
+		// show lines as they arrive when piped from a running process
+		if len(scanner.Bytes()) > 0 && !bufferedInput() {
+			out.Flush()
+		}
+	}
+	return scanner.Err()
+}
+
+// isCharDevice indicates whether file is a terminal
+func isCharDevice(file *os.File) bool {
+	stat, err := file.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+// bufferedInput indicates whether stdin is a regular file, which need not be flushed per line
+func bufferedInput() bool {
+	stat, err := os.Stdin.Stat()
+	return err == nil && stat.Mode().IsRegular()
+}
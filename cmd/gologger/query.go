@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fun-think/gologger"
+)
+
+// filter selects records
+type filter struct {
+	level  gologger.Level
+	since  time.Time
+	until  time.Time
+	caller string
+	app    string
+	host   string
+	grep   *regexp.Regexp
+}
+
+func (f *filter) match(r *record) bool {
+	if !r.parsed() {
+		// lines of unknown layout only match without filters
+		return f.level == gologger.DEBUG && f.since.IsZero() && f.until.IsZero() &&
+			f.caller == "" && f.app == "" && f.host == "" && (f.grep == nil || f.grep.MatchString(r.Msg))
+	}
+	if r.Level > f.level {
+		return false
+	}
+	if !f.since.IsZero() && r.Time.Before(f.since) {
+		return false
+	}
+	if !f.until.IsZero() && !r.Time.Before(f.until) {
+		return false
+	}
+	if f.caller != "" && !strings.Contains(r.caller(), f.caller) && !strings.Contains(r.Func, f.caller) {
+		return false
+	}
+	if f.app != "" && r.App != f.app {
+		return false
+	}
+	if f.host != "" && r.Host != f.host {
+		return false
+	}
+	return f.grep == nil || f.grep.MatchString(r.Msg)
+}
+
+// parseTime parses RFC3339, the log time format, or a duration ago like 1h
+func parseTime(s string, p *parser) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{p.timeFormat, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, p.location); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
+
+func query(args []string) error {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: gologger [query] [flags] [file|dir ...]\n\n"+
+			"Reads TextFormat and JSONFormat lines from files, their rotated siblings\n"+
+			"and gzipped archives in chronological order, or stdin without files.\n\n")
+		fs.PrintDefaults()
+	}
+	level := fs.String("level", "DEBUG", "show entries at this `level` or more severe")
+	since := fs.String("since", "", "show entries at or after `time`, RFC3339, log time or duration ago like 1h")
+	until := fs.String("until", "", "show entries before `time`")
+	caller := fs.String("caller", "", "show entries whose file:line or function contains `text`")
+	app := fs.String("app", "", "show entries of `app`")
+	host := fs.String("host", "", "show entries of `host`")
+	grep := fs.String("grep", "", "show entries whose message matches `regexp`")
+	follow := fs.Bool("f", false, "follow the newest file across rotations")
+	output := fs.String("o", "text", "output `format`: text, json or logfmt")
+	timeFormat := fs.String("time-format", "2006-01-02 15:04:05.000", "time `layout` of the log files")
+	fs.Parse(args)
+
+	p := &parser{timeFormat: *timeFormat, location: time.Local}
+	f := new(filter)
+	var err error
+	if f.level, err = gologger.ParseLevel(*level); err != nil {
+		return err
+	}
+	if f.since, err = parseTime(*since, p); err != nil {
+		return err
+	}
+	if f.until, err = parseTime(*until, p); err != nil {
+		return err
+	}
+	f.caller, f.app, f.host = *caller, *app, *host
+	if *grep != "" {
+		if f.grep, err = regexp.Compile(*grep); err != nil {
+			return err
+		}
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	render, err := renderer(*output, *timeFormat)
+	if err != nil {
+		return err
+	}
+	emit := func(r *record) error {
+		if !f.match(r) {
+			return nil
+		}
+		render(out, r)
+		if *follow {
+			return out.Flush()
+		}
+		return nil
+	}
+
+	if fs.NArg() == 0 {
+		return readRecords(os.Stdin, p, emit)
+	}
+
+	files, err := logFiles(fs.Args())
+	if err != nil {
+		return err
+	}
+	for i, name := range files {
+		last := i == len(files)-1
+		if last && *follow && !strings.HasSuffix(name, ".gz") {
+			out.Flush()
+			fl := &follower{args: fs.Args(), parser: p, interval: 200 * time.Millisecond}
+			return fl.follow(name, 0, emit)
+		}
+		if err := readFile(name, p, emit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readFile(name string, p *parser, emit func(*record) error) error {
+	r, err := openLog(name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return readRecords(r, p, emit)
+}
+
+// renderer returns a function writing records in format
+func renderer(format, timeFormat string) (func(w io.Writer, r *record), error) {
+	switch format {
+	case "text":
+		return func(w io.Writer, r *record) { renderText(w, r, timeFormat) }, nil
+	case "json":
+		return func(w io.Writer, r *record) { renderJSON(w, r, timeFormat) }, nil
+	case "logfmt":
+		return func(w io.Writer, r *record) { renderLogfmt(w, r, timeFormat) }, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q", format)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fun-think/gologger"
+	"github.com/fun-think/gologger/format"
+)
+
+func TestParse(t *testing.T) {
+	p := &parser{timeFormat: "2006-01-02 15:04:05.000", location: time.Local}
+
+	for _, f := range []gologger.Format{
+		&format.TextFormat{AppName: "app", ShowFunc: true},
+		&format.JSONFormat{AppName: "app", ShowFunc: true},
+	} {
+		var buf bytes.Buffer
+		logger := &gologger.Logger{Level: gologger.DEBUG, Format: f, Output: &buf, StacktraceLevel: gologger.ERROR}
+		logger.Warn("first message")
+		logger.Error("second")
+
+		var records []*record
+		readRecords(&buf, p, func(r *record) error {
+			records = append(records, r)
+			return nil
+		})
+		if len(records) != 2 {
+			t.Fatalf("%T: parsed %d records", f, len(records))
+		}
+		r := records[0]
+		if !r.parsed() || r.Level != gologger.WARN || r.App != "app" || r.PID != os.Getpid() ||
+			r.Msg != "first message" || !strings.HasSuffix(r.File, "query_test.go") || r.Func != "gologger.TestParse" {
+			t.Errorf("%T: unexpected record %+v", f, r)
+		}
+		if !strings.Contains(records[1].Stack, "TestParse") {
+			t.Errorf("%T: stack not joined: %q", f, records[1].Stack)
+		}
+	}
+
+	if r := p.parse("not a log line"); r.parsed() || r.Msg != "not a log line" {
+		t.Errorf("unexpected record %+v", r)
+	}
+}
+
+func TestLogFiles(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "app")
+
+	write := func(name, content string, age time.Duration) {
+		data := []byte(content)
+		if strings.HasSuffix(name, ".gz") {
+			var buf bytes.Buffer
+			zw := gzip.NewWriter(&buf)
+			zw.Write(data)
+			zw.Close()
+			data = buf.Bytes()
+		}
+		if err := os.WriteFile(name, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		mtime := time.Now().Add(-age)
+		os.Chtimes(name, mtime, mtime)
+	}
+	write(base+".1.log", "b\n", 2*time.Hour)
+	write(base+".0.log", "c\n", time.Hour)
+	write(base+".2.log.gz", "a\n", 3*time.Hour)
+	os.Symlink("app.0.log", base)
+
+	files, err := logFiles([]string{base})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for _, name := range files {
+		readFile(name, &parser{timeFormat: time.RFC3339}, func(r *record) error {
+			lines = append(lines, r.Msg)
+			return nil
+		})
+	}
+	if got := strings.Join(lines, ""); got != "abc" {
+		t.Errorf("read %q from %v, want abc", got, files)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/fun-think/gologger/writer"
+)
+
+func verify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: gologger verify [flags] file|dir ...\n\n"+
+			"Verifies the hash chain of files written by writer.ChainWriter, across\n"+
+			"rotated files in chronological order, and reports the first tampered line.\n\n")
+		fs.PrintDefaults()
+	}
+	keyFile := fs.String("key-file", "", "read the HMAC key from `file`")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("no files")
+	}
+
+	v := new(writer.ChainVerifier)
+	if *keyFile != "" {
+		key, err := os.ReadFile(*keyFile)
+		if err != nil {
+			return err
+		}
+		v.Key = bytes.TrimRight(key, "\r\n")
+	}
+
+	files, err := logFiles(fs.Args())
+	if err != nil {
+		return err
+	}
+	for _, name := range files {
+		r, err := openLog(name)
+		if err != nil {
+			return err
+		}
+		err = v.Verify(name, r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("%d files verified\n", len(files))
+	return nil
+}
@@ -0,0 +1,29 @@
+package gologger
+
+import (
+	"io"
+	"time"
+)
+
+// Entry is a single log event passed to Format
+type Entry struct {
+	Logger  *Logger
+	Time    time.Time
+	Level   Level
+	Message string
+	Fields  Fields // shared with Logger, must not be modified
+	Caller  Caller
+
+	// Stack is set when Level reaches Logger.StacktraceLevel, and for PANIC
+	Stack Stack
+
+	// Output is the writer the formatted entry is written to
+	Output io.Writer
+}
+
+// EntryWriter is implemented by outputs which need the entry along with
+// the formatted line, e.g. to map Level to a protocol severity
+type EntryWriter interface {
+	io.Writer
+	WriteEntry(entry *Entry, p []byte) (n int, err error)
+}
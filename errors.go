@@ -0,0 +1,107 @@
+package gologger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrorHandler is called when an entry can not be written to an output
+type ErrorHandler func(err error)
+
+// WriteError is passed to ErrorHandler when Output.Write fails
+type WriteError struct {
+	Output io.Writer
+	Err    error
+}
+
+// Error implements error
+func (e *WriteError) Error() string {
+	return fmt.Sprintf("failed to write log, %v", e.Err)
+}
+
+// Unwrap returns the underlying write error
+func (e *WriteError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorReporter reports internal errors to Output, the same message is
+// printed at most once per Interval followed by the count of suppressed ones
+type ErrorReporter struct {
+	Output   io.Writer     // default is os.Stderr
+	Interval time.Duration // default is 1 minute
+
+	mutex  sync.Mutex
+	recent map[string]*reportState
+}
+
+type reportState struct {
+	last       time.Time
+	suppressed int
+}
+
+// Report prints err unless the same message was printed within Interval
+func (r *ErrorReporter) Report(err error) {
+	msg := err.Error()
+	now := time.Now()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	interval := r.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	if r.recent == nil {
+		r.recent = make(map[string]*reportState)
+	}
+
+	state, ok := r.recent[msg]
+	if ok && now.Sub(state.last) < interval {
+		state.suppressed++
+		return
+	}
+
+	output := r.Output
+	if output == nil {
+		output = os.Stderr
+	}
+
+	// forget expired messages, so the map does not grow unbounded, and
+	// report how often they were suppressed
+	for k, s := range r.recent {
+		if k != msg && now.Sub(s.last) >= interval {
+			if s.suppressed > 0 {
+				fmt.Fprintf(output, "%s (suppressed %d times)\n", k, s.suppressed)
+			}
+			delete(r.recent, k)
+		}
+	}
+	if ok && state.suppressed > 0 {
+		fmt.Fprintf(output, "%s (suppressed %d times)\n", msg, state.suppressed)
+	} else {
+		fmt.Fprintln(output, msg)
+	}
+	r.recent[msg] = &reportState{last: now}
+}
+
+// defaultReporter is used by loggers without ErrorHandler
+var defaultReporter = new(ErrorReporter)
+
+// handleError passes err to ErrorHandler, must be called without lock held
+func (l *Logger) handleError(output io.Writer, err error) {
+	werr := &WriteError{Output: output, Err: err}
+	if l.ErrorHandler != nil {
+		l.ErrorHandler(werr)
+	} else {
+		defaultReporter.Report(werr)
+	}
+}
+
+// FailedWrites returns the number of writes failed since created, child
+// loggers share the counter with their parent
+func (l *Logger) FailedWrites() uint64 {
+	return l.lockOwner().counters.writeErrors.Load()
+}
@@ -0,0 +1,84 @@
+package gologger
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+)
+
+// FatalAction is run after a FATAL entry is written
+type FatalAction func(entry *Entry)
+
+// ExitAction runs exit handlers and exits with code through Exit
+func ExitAction(code int) FatalAction {
+	return func(*Entry) {
+		RunExitHandlers()
+		Exit(code)
+	}
+}
+
+// GoexitAction stops the goroutine calling Fatal with runtime.Goexit, deferred calls are run
+func GoexitAction(*Entry) {
+	runtime.Goexit()
+}
+
+// NoopAction does nothing, Fatal returns to its caller
+func NoopAction(*Entry) {}
+
+var (
+	exitMutex    sync.Mutex
+	exitHandlers []*func()
+)
+
+// RegisterExitHandler adds handler run by ExitAction before exiting,
+// e.g. to flush buffered writers or close files. unregister removes it
+func RegisterExitHandler(handler func()) (unregister func()) {
+	exitMutex.Lock()
+	defer exitMutex.Unlock()
+
+	// a pointer, as funcs can not be compared
+	h := &handler
+	exitHandlers = append(exitHandlers, h)
+	return func() {
+		exitMutex.Lock()
+		defer exitMutex.Unlock()
+		for i, registered := range exitHandlers {
+			if registered == h {
+				exitHandlers = append(exitHandlers[:i:i], exitHandlers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// RunExitHandlers runs registered exit handlers in order, a panicking
+// handler is reported to stderr and does not stop the others
+func RunExitHandlers() {
+	exitMutex.Lock()
+	handlers := append([]*func(){}, exitHandlers...)
+	exitMutex.Unlock()
+
+	for _, handler := range handlers {
+		runExitHandler(*handler)
+	}
+}
+
+func runExitHandler(handler func()) {
+	defer func() {
+		if err := recover(); err != nil {
+			fmt.Fprintf(os.Stderr, "exit handler panicked, %v\n", err)
+		}
+	}()
+	handler()
+}
+
+// PanicError is the value passed to panic() by Panic, Panicln and Panicf
+type PanicError struct {
+	Entry *Entry
+}
+
+// Error implements error
+func (e *PanicError) Error() string {
+	return e.Entry.Message
+}
@@ -0,0 +1,52 @@
+package gologger
+
+import (
+	"context"
+	"sort"
+)
+
+// Fields are key-value pairs attached to entries
+type Fields map[string]any
+
+// Keys returns keys of fields in sorted order
+func (f Fields) Keys() []string {
+	keys := make([]string, 0, len(f))
+	for k := range f {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// WithFields returns a child Logger adding fields to every entry
+func (l *Logger) WithFields(fields Fields) *Logger {
+	c := l.clone()
+	c.fields = make(Fields, len(l.fields)+len(fields))
+	for k, v := range l.fields {
+		c.fields[k] = v
+	}
+	for k, v := range fields {
+		c.fields[k] = v
+	}
+	return c
+}
+
+// WithField returns a child Logger adding key=value to every entry
+func (l *Logger) WithField(key string, value any) *Logger {
+	return l.WithFields(Fields{key: value})
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying logger
+func NewContext(ctx context.Context, logger *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the Logger carried by ctx, or Default
+func FromContext(ctx context.Context) *Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*Logger); ok {
+		return logger
+	}
+	return Default
+}
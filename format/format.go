@@ -1,40 +1,84 @@
 package format
 
 import (
+	"fmt"
 	"io"
 	"os"
+	"reflect"
 	"runtime"
+	"strconv"
 	"strings"
+
+	"github.com/fun-think/gologger"
 )
 
-// FileLineCaller returns file and line for caller
+// FileLineCaller returns file and line for caller as pkg/file.go, skipping
+// frames of gologger
+//
+// Deprecated: formats get the caller captured by the logger as Entry.Caller
 func FileLineCaller(skip int) (file string, line int) {
 	for i := 0; i < 10; i++ {
-		_, file, line, ok := runtime.Caller(skip + i)
+		pc, file, line, ok := runtime.Caller(skip + i)
 		if !ok {
 			return "???", 0
 		}
-
-		// file = pkg/file.go
-		n := 0
-		for i := len(file) - 1; i > 0; i-- {
-			if file[i] == '/' {
-				n++
-				if n >= 2 {
-					file = file[i+1:]
-					break
-				}
-			}
+		c := gologger.Caller{PC: pc, File: file, Line: line}
+		if fn := runtime.FuncForPC(pc); fn != nil {
+			c.Function = fn.Name()
 		}
-
-		if !strings.HasPrefix(file, "logs/") {
-			return file, line
+		if !strings.HasPrefix(c.Function, loggerPkg+".") && !strings.HasPrefix(c.Function, loggerPkg+"/format.") {
+			return c.ShortFile(), c.Line
 		}
 	}
-
 	return "???", 0
 }
 
+// loggerPkg is the import path of gologger
+var loggerPkg = reflect.TypeOf(gologger.Logger{}).PkgPath()
+
+// formatCaller returns file:line of caller, file is absolute if fullPath
+func formatCaller(c gologger.Caller, fullPath bool) (file string, line int) {
+	if !c.Defined() {
+		return "???", 0
+	}
+	if fullPath {
+		return c.File, c.Line
+	}
+	return c.ShortFile(), c.Line
+}
+
+// appendCaller appends file:line or file:line(func) of caller
+func appendCaller(dst []byte, c gologger.Caller, fullPath, showFunc bool) []byte {
+	file, line := formatCaller(c, fullPath)
+	dst = append(dst, file...)
+	dst = append(dst, ':')
+	dst = strconv.AppendInt(dst, int64(line), 10)
+	if showFunc && c.Defined() {
+		dst = append(dst, '(')
+		dst = append(dst, c.ShortFunction()...)
+		dst = append(dst, ')')
+	}
+	return dst
+}
+
+// fieldValue returns v as text, quoted if it contains spaces, quotes or '=',
+// or unsafe characters if sanitize
+func fieldValue(v any, sanitize bool) string {
+	s := fmt.Sprint(v)
+	if s == "" || strings.ContainsAny(s, " =\"\t\r\n") || sanitize && !isSafe(s) {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// jsonValue returns v ready for json encoding, errors encode as {} otherwise
+func jsonValue(v any) any {
+	if err, ok := v.(error); ok {
+		return err.Error()
+	}
+	return v
+}
+
 // IsTerminal returns whether is a valid tty for io.Writer
 func IsTerminal(w io.Writer) bool {
 	switch w.(type) {
@@ -7,7 +7,6 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
-	"time"
 
 	"github.com/fun-think/gologger"
 )
@@ -16,6 +15,11 @@ import (
 type JSONFormat struct {
 	AppName    string
 	TimeFormat string
+	FullPath   bool // output absolute file path instead of pkg/file.go
+	ShowFunc   bool // output function name of caller
+
+	// StackFrames outputs stacktrace as an array of frames instead of a string
+	StackFrames bool
 
 	init sync.Once
 	host string
@@ -23,8 +27,9 @@ type JSONFormat struct {
 }
 
 // Format implements log.Formatter
-func (f *JSONFormat) Format(level gologger.Level, msg string, logger *gologger.Logger) []byte {
-	// output fields: time level host app pid file line msg
+func (f *JSONFormat) Format(entry *gologger.Entry) []byte {
+	// output fields: time level host app pid file line [func] msg [stacktrace] [fields ...]
+	// fields named like an output field are prefixed with "fields."
 
 	f.init.Do(func() {
 		if f.AppName == "" {
@@ -38,19 +43,35 @@ func (f *JSONFormat) Format(level gologger.Level, msg string, logger *gologger.L
 		f.pid = os.Getpid()
 	})
 
-	data := make(map[string]any, 8)
+	data := make(map[string]any, 10+len(entry.Fields))
 
 	// file, line
-	file, line := FileLineCaller(4)
+	file, line := formatCaller(entry.Caller, f.FullPath)
 
-	data["time"] = time.Now().Format(f.TimeFormat)
-	data["level"] = level.String()
+	data["time"] = entry.Time.Format(f.TimeFormat)
+	data["level"] = entry.Level.String()
 	data["host"] = f.host
 	data["app"] = f.AppName
 	data["pid"] = f.pid
 	data["file"] = file
 	data["line"] = line
-	data["msg"] = msg
+	if f.ShowFunc && entry.Caller.Defined() {
+		data["func"] = entry.Caller.ShortFunction()
+	}
+	data["msg"] = entry.Message
+	if len(entry.Stack) > 0 {
+		if f.StackFrames {
+			data["stacktrace"] = stackFrames(entry.Stack)
+		} else {
+			data["stacktrace"] = entry.Stack.String()
+		}
+	}
+	for k, v := range entry.Fields {
+		if _, ok := data[k]; ok {
+			k = "fields." + k
+		}
+		data[k] = jsonValue(v)
+	}
 
 	serialized, err := marshal(data)
 	if err != nil {
@@ -59,6 +80,20 @@ func (f *JSONFormat) Format(level gologger.Level, msg string, logger *gologger.L
 	return serialized
 }
 
+type jsonFrame struct {
+	Func string `json:"func"`
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+func stackFrames(stack gologger.Stack) []jsonFrame {
+	frames := make([]jsonFrame, len(stack))
+	for i, c := range stack {
+		frames[i] = jsonFrame{Func: c.Function, File: c.File, Line: c.Line}
+	}
+	return frames
+}
+
 func marshal(v any) ([]byte, error) {
 	var buf bytes.Buffer
 	encoder := json.NewEncoder(&buf)
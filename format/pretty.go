@@ -0,0 +1,174 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/fun-think/gologger"
+)
+
+// PrettyFormat re-renders JSONFormat lines in TextFormat style for humans
+type PrettyFormat struct {
+	Color       bool
+	MaxColWidth int // columns wider than this are not padded, default is 40
+
+	// widths of host, app and caller columns seen so far
+	widths [3]int
+}
+
+// Render writes line re-rendered, lines which are not only a json object are
+// written untouched. Decoded values are sanitized like TextFormat.Sanitize,
+// so they can not add lines or terminal escapes
+func (f *PrettyFormat) Render(w io.Writer, line []byte) error {
+	trimmed := bytes.TrimRight(line, "\r\n")
+
+	var data map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(trimmed))
+	decoder.UseNumber()
+	if !bytes.HasPrefix(trimmed, []byte("{")) || decoder.Decode(&data) != nil ||
+		len(bytes.TrimSpace(trimmed[decoder.InputOffset():])) > 0 {
+		_, err := w.Write(append(trimmed, '\n'))
+		return err
+	}
+
+	var buf bytes.Buffer
+
+	// time
+	f.dim(&buf, safeValue(data["time"]))
+
+	// level, padded to the longest level name
+	levelName := safeValue(data["level"])
+	buf.WriteByte(' ')
+	if level, err := gologger.ParseLevel(levelName); err == nil && f.Color {
+		buf.WriteString(level.ColorString())
+	} else {
+		buf.WriteString(levelName)
+	}
+	buf.WriteString(strings.Repeat(" ", pad(5, len(levelName))))
+
+	// host, app, pid
+	f.column(&buf, 0, safeValue(data["host"]))
+	f.column(&buf, 1, safeValue(data["app"])+" "+safeValue(data["pid"]))
+
+	// file:line(func)
+	caller := safeValue(data["file"]) + ":" + safeValue(data["line"])
+	if fn := safeValue(data["func"]); fn != "" {
+		caller += "(" + fn + ")"
+	}
+	f.column(&buf, 2, caller)
+
+	// msg
+	buf.WriteByte(' ')
+	buf.WriteString(safeValue(data["msg"]))
+
+	// fields, nested objects are flattened to dotted keys
+	fields := map[string]any{}
+	for k, v := range data {
+		switch k {
+		case "time", "level", "host", "app", "pid", "file", "line", "func", "msg", "stacktrace":
+		default:
+			flatten(fields, k, v)
+		}
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		buf.WriteByte(' ')
+		if f.Color {
+			buf.WriteString("\033[36m" + sanitize(k, 0, "") + "\033[0m=")
+		} else {
+			buf.WriteString(sanitize(k, 0, "") + "=")
+		}
+		buf.WriteString(fieldValue(fields[k], true))
+	}
+	buf.WriteByte('\n')
+
+	// stack, indented like TextFormat
+	switch stack := data["stacktrace"].(type) {
+	case string:
+		for _, l := range strings.Split(strings.TrimRight(stack, "\n"), "\n") {
+			f.dim(&buf, "\t"+sanitize(l, 0, ""))
+			buf.WriteByte('\n')
+		}
+	case []any:
+		for _, frame := range stack {
+			frame, _ := frame.(map[string]any)
+			f.dim(&buf, "\t"+safeValue(frame["func"])+"\n\t\t"+safeValue(frame["file"])+":"+safeValue(frame["line"]))
+			buf.WriteByte('\n')
+		}
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// column writes s padded to the widest value of column i seen so far
+func (f *PrettyFormat) column(buf *bytes.Buffer, i int, s string) {
+	maxWidth := f.MaxColWidth
+	if maxWidth <= 0 {
+		maxWidth = 40
+	}
+	if len(s) > f.widths[i] && len(s) <= maxWidth {
+		f.widths[i] = len(s)
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(s)
+	buf.WriteString(strings.Repeat(" ", pad(f.widths[i], len(s))))
+}
+
+// dim writes s in gray if colored
+func (f *PrettyFormat) dim(buf *bytes.Buffer, s string) {
+	if f.Color {
+		buf.WriteString("\033[90m" + s + "\033[0m")
+	} else {
+		buf.WriteString(s)
+	}
+}
+
+func pad(width, n int) int {
+	if n >= width {
+		return 0
+	}
+	return width - n
+}
+
+// flatten adds v to fields, objects as key.subkey
+func flatten(fields map[string]any, key string, v any) {
+	obj, ok := v.(map[string]any)
+	if !ok || len(obj) == 0 {
+		if arr, ok := v.([]any); ok {
+			b, _ := json.Marshal(arr)
+			v = string(b)
+		}
+		fields[key] = v
+		return
+	}
+	for k, sub := range obj {
+		flatten(fields, key+"."+k, sub)
+	}
+}
+
+func stringValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// safeValue returns v as string with line breaks and terminal escapes escaped
+func safeValue(v any) string {
+	return sanitize(stringValue(v), 0, "")
+}
@@ -0,0 +1,46 @@
+package format_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fun-think/gologger/format"
+)
+
+func TestPrettyFormat(t *testing.T) {
+	var buf bytes.Buffer
+	f := new(format.PrettyFormat)
+	for _, line := range []string{
+		`{"time":"2026-10-19 10:00:00.000","level":"ERROR","host":"web-1","app":"api","pid":12,"file":"main/a.go","line":3,"msg":"boom","user":{"id":1},"stacktrace":"main.f\n\t/x/a.go:3\n"}`,
+		`not json`,
+		`{"time":"2026-10-19 10:00:01.000","level":"INFO","host":"w","app":"api","pid":12,"file":"main/a.go","line":4,"msg":"ok"}`,
+	} {
+		f.Render(&buf, []byte(line+"\n"))
+	}
+
+	want := "2026-10-19 10:00:00.000 ERROR web-1 api 12 main/a.go:3 boom user.id=1\n" +
+		"\tmain.f\n" +
+		"\t\t/x/a.go:3\n" +
+		"not json\n" +
+		"2026-10-19 10:00:01.000 INFO  w     api 12 main/a.go:4 ok\n"
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestPrettyFormatEscapes(t *testing.T) {
+	var buf bytes.Buffer
+	f := new(format.PrettyFormat)
+	for _, line := range []string{
+		`{"time":"t","level":"INFO","msg":"a\nFAKE \u001b[31mred","user\u001b[2J":"x\u001b]0;title\u0007"}`,
+		`{"msg":"object"} trailing`,
+	} {
+		f.Render(&buf, []byte(line+"\n"))
+	}
+
+	want := `t INFO     : a\nFAKE \x1b[31mred user\x1b[2J="x\x1b]0;title\a"` + "\n" +
+		`{"msg":"object"} trailing` + "\n"
+	if buf.String() != want {
+		t.Errorf("got\n%q\nwant\n%q", buf.String(), want)
+	}
+}
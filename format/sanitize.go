@@ -0,0 +1,67 @@
+package format
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultTruncateMarker is appended to messages cut at MaxMessageLength
+const DefaultTruncateMarker = "...[truncated]"
+
+// sanitize escapes line breaks, control characters and ESC, replaces invalid
+// UTF-8 and cuts s to maxLen bytes if maxLen > 0
+func sanitize(s string, maxLen int, marker string) string {
+	if maxLen > 0 && len(s) > maxLen {
+		cut := maxLen
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		s = s[:cut] + marker
+	}
+	if isSafe(s) {
+		return s
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(s) + 8)
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		switch {
+		case r == utf8.RuneError && size == 1:
+			sb.WriteRune(utf8.RuneError)
+		case r == '\n':
+			sb.WriteString(`\n`)
+		case r == '\r':
+			sb.WriteString(`\r`)
+		case r == '\t':
+			sb.WriteByte('\t')
+		case r < 0x20 || r == 0x7f:
+			sb.WriteString(`\x`)
+			if r < 0x10 {
+				sb.WriteByte('0')
+			}
+			sb.WriteString(strconv.FormatInt(int64(r), 16))
+		case unicode.IsControl(r) || r == '\u2028' || r == '\u2029':
+			sb.WriteString(`\u`)
+			sb.WriteString(strconv.FormatInt(int64(r)|0x10000, 16)[1:])
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// isSafe indicates whether s is valid UTF-8 without line breaks or control characters
+func isSafe(s string) bool {
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 || r != '\t' && unicode.IsControl(r) || r == '\u2028' || r == '\u2029' {
+			return false
+		}
+		i += size
+	}
+	return true
+}
@@ -6,7 +6,6 @@ import (
 	"path/filepath"
 	"strconv"
 	"sync"
-	"time"
 
 	"github.com/fun-think/gologger"
 )
@@ -24,6 +23,15 @@ type TextFormat struct {
 	AppName    string
 	TimeFormat string
 	IsTerminal bool
+	FullPath   bool // output absolute file path instead of pkg/file.go
+	ShowFunc   bool // output function name of caller
+
+	// Sanitize escapes line breaks, control characters and ESC in messages
+	// and fields, and replaces invalid UTF-8, so user input can not forge
+	// lines or terminal escapes
+	Sanitize         bool
+	MaxMessageLength int    // cut longer messages if Sanitize, 0 is unlimited
+	TruncateMarker   string // appended to cut messages, default is DefaultTruncateMarker
 
 	init sync.Once
 	host []byte
@@ -32,8 +40,8 @@ type TextFormat struct {
 }
 
 // Format implements log.Formatter
-func (f *TextFormat) Format(level gologger.Level, msg string, logger *gologger.Logger) []byte {
-	// output format: DATE LEVEL HOST APP PID file:line message
+func (f *TextFormat) Format(entry *gologger.Entry) []byte {
+	// output format: DATE LEVEL HOST APP PID file:line[(func)] message [key=value ...]
 	// 2001-10-10T12:00:00,000+0800 INFO web-1 app 1234 main/main.go:1234 message ...
 
 	f.init.Do(func() {
@@ -45,8 +53,11 @@ func (f *TextFormat) Format(level gologger.Level, msg string, logger *gologger.L
 		if f.TimeFormat == "" {
 			f.TimeFormat = "2006-01-02 15:04:05.000"
 		}
+		if f.TruncateMarker == "" {
+			f.TruncateMarker = DefaultTruncateMarker
+		}
 
-		f.IsTerminal = IsTerminal(logger.Output)
+		f.IsTerminal = IsTerminal(entry.Output)
 
 		host, _ := os.Hostname()
 		f.host = []byte(host)
@@ -59,15 +70,15 @@ func (f *TextFormat) Format(level gologger.Level, msg string, logger *gologger.L
 	defer fmtBuffer.Put(buf)
 
 	// timestamp
-	timeStr := time.Now().Format(f.TimeFormat)
+	timeStr := entry.Time.Format(f.TimeFormat)
 	buf.WriteString(timeStr)
 
 	// level
 	buf.WriteByte(' ')
 	if f.IsTerminal {
-		buf.WriteString(level.ColorString())
+		buf.WriteString(entry.Level.ColorString())
 	} else {
-		buf.WriteString(level.String())
+		buf.WriteString(entry.Level.String())
 	}
 
 	// host
@@ -83,18 +94,44 @@ func (f *TextFormat) Format(level gologger.Level, msg string, logger *gologger.L
 	buf.Write(f.pid)
 
 	// file, line
-	file, line := FileLineCaller(4)
+	var scratch [128]byte
 	buf.WriteByte(' ')
-	buf.WriteString(file)
-	buf.WriteByte(':')
-	buf.WriteString(strconv.Itoa(line))
+	buf.Write(appendCaller(scratch[:0], entry.Caller, f.FullPath, f.ShowFunc))
 
 	// msg
 	buf.WriteByte(' ')
-	buf.WriteString(msg)
+	if f.Sanitize {
+		buf.WriteString(sanitize(entry.Message, f.MaxMessageLength, f.TruncateMarker))
+	} else {
+		buf.WriteString(entry.Message)
+	}
+
+	// fields, values are quoted with escapes if needed
+	for _, k := range entry.Fields.Keys() {
+		buf.WriteByte(' ')
+		if f.Sanitize {
+			buf.WriteString(sanitize(k, 0, ""))
+		} else {
+			buf.WriteString(k)
+		}
+		buf.WriteByte('=')
+		buf.WriteString(fieldValue(entry.Fields[k], f.Sanitize))
+	}
 
 	// newline
 	buf.WriteByte('\n')
 
-	return buf.Bytes()
+	// stack, indented to keep it apart from log lines
+	for _, c := range entry.Stack {
+		buf.WriteByte('\t')
+		buf.WriteString(c.Function)
+		buf.WriteString("\n\t\t")
+		buf.WriteString(c.File)
+		buf.WriteByte(':')
+		buf.WriteString(strconv.Itoa(c.Line))
+		buf.WriteByte('\n')
+	}
+
+	// buf is reused by other goroutines once put back
+	return append([]byte(nil), buf.Bytes()...)
 }
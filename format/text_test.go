@@ -0,0 +1,45 @@
+package format_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/fun-think/gologger"
+	"github.com/fun-think/gologger/format"
+)
+
+func TestTextFormatSanitize(t *testing.T) {
+	f := &format.TextFormat{Sanitize: true, MaxMessageLength: 40}
+	line := f.Format(&gologger.Entry{
+		Level:   gologger.INFO,
+		Message: "login bob\n2023-01-01 ERROR forged \x1b[31mred\xff",
+		Fields:  gologger.Fields{"user": "a\rb"},
+		Output:  new(bytes.Buffer),
+	})
+
+	if n := bytes.Count(line, []byte("\n")); n != 1 {
+		t.Fatalf("got %d lines: %q", n, line)
+	}
+	for _, want := range []string{
+		`login bob\n2023-01-01 ERROR forged \x1b[31mr` + format.DefaultTruncateMarker,
+		`user="a\rb"`,
+	} {
+		if !bytes.Contains(line, []byte(want)) {
+			t.Errorf("%q does not contain %q", line, want)
+		}
+	}
+
+	f = &format.TextFormat{Sanitize: true}
+	line = f.Format(&gologger.Entry{Level: gologger.INFO, Message: "bad \xff utf8", Output: new(bytes.Buffer)})
+	if !strings.Contains(string(line), "bad � utf8") {
+		t.Errorf("invalid UTF-8 not replaced: %q", line)
+	}
+}
+
+func TestFileLineCaller(t *testing.T) {
+	file, line := format.FileLineCaller(1)
+	if file != "format/text_test.go" || line == 0 {
+		t.Errorf("got %s:%d", file, line)
+	}
+}
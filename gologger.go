@@ -59,17 +59,42 @@ type LogInterface interface {
 
 // Format is a interface used to implement a custom Format
 type Format interface {
+	Format(entry *Entry) []byte
+}
+
+// LegacyFormat is the Format interface before entries carried the caller,
+// time and fields
+//
+// Deprecated: implement Format, or wrap with AdaptLegacyFormat meanwhile
+type LegacyFormat interface {
 	Format(level Level, msg string, logger *Logger) []byte
 }
 
+// AdaptLegacyFormat returns f as Format. The caller is no longer found by
+// f through runtime.Caller, use Entry.Caller when porting it
+//
+// Deprecated: implement Format
+func AdaptLegacyFormat(f LegacyFormat) Format {
+	return legacyFormat{f}
+}
+
+type legacyFormat struct {
+	f LegacyFormat
+}
+
+// Format implements Format
+func (f legacyFormat) Format(entry *Entry) []byte {
+	return f.f.Format(entry.Level, entry.Message, entry.Logger)
+}
+
 // simpleFormat is default formmatter
 type simpleFormat struct {
 }
 
 // Format implements log.Format
-func (f *simpleFormat) Format(level Level, msg string, logger *Logger) []byte {
-	time := time.Now().Format("15:04:05.000")
-	return []byte(fmt.Sprintf("%s %s %s\n", time, level.String(), msg))
+func (f *simpleFormat) Format(entry *Entry) []byte {
+	time := entry.Time.Format("15:04:05.000")
+	return []byte(fmt.Sprintf("%s %s %s\n", time, entry.Level.String(), entry.Message))
 }
 
 // Level type
@@ -220,12 +245,12 @@ func Error(obj ...any) {
 	Default.Error(obj...)
 }
 
-// Panic outputs message, and followed by a call to panic() Arguments are handled by fmt.Sprint
+// Panic outputs message, and followed by a call to panic() with a *PanicError Arguments are handled by fmt.Sprint
 func Panic(obj ...any) {
 	Default.Panic(obj...)
 }
 
-// Fatal outputs message, and followed by a call to os.Exit(1) Arguments are handled by fmt.Sprint
+// Fatal outputs message, and followed by a call to OnFatal, os.Exit(1) by default Arguments are handled by fmt.Sprint
 func Fatal(obj ...any) {
 	Default.Fatal(obj...)
 }
@@ -255,12 +280,12 @@ func Errorln(obj ...any) {
 	Default.Errorln(obj...)
 }
 
-// Panicln outputs message and followed by a call to panic(), Arguments are handled by fmt.Sprintln
+// Panicln outputs message and followed by a call to panic() with a *PanicError, Arguments are handled by fmt.Sprintln
 func Panicln(obj ...any) {
 	Default.Panicln(obj...)
 }
 
-// Fatalln outputs message and followed by a call to os.Exit(1), Arguments are handled by fmt.Sprintln
+// Fatalln outputs message and followed by a call to OnFatal, os.Exit(1) by default, Arguments are handled by fmt.Sprintln
 func Fatalln(obj ...any) {
 	Default.Fatalln(obj...)
 }
@@ -290,12 +315,12 @@ func Errorf(msg string, args ...any) {
 	Default.Errorf(msg, args...)
 }
 
-// Panicf outputs message and followed by a call to panic(), Arguments are handled by fmt.Sprintf
+// Panicf outputs message and followed by a call to panic() with a *PanicError, Arguments are handled by fmt.Sprintf
 func Panicf(msg string, args ...any) {
 	Default.Panicf(msg, args...)
 }
 
-// Fatalf outputs message and followed by a call to os.Exit(1), Arguments are handled by fmt.Sprintf
+// Fatalf outputs message and followed by a call to OnFatal, os.Exit(1) by default, Arguments are handled by fmt.Sprintf
 func Fatalf(msg string, args ...any) {
 	Default.Fatalf(msg, args...)
 }
@@ -309,6 +334,39 @@ type Logger struct {
 	Level  Level
 	Format Format
 	Output io.Writer
+
+	// Sinks replaces Format and Output when not empty, a failed write to
+	// one sink does not prevent writes to the others
+	Sinks []*Sink
+
+	// Redaction masks sensitive data in messages and fields, nil disables it.
+	// Arguments implementing Redactor are always redacted
+	Redaction *Redaction
+
+	// OnFatal is run after a FATAL entry is written, default is ExitAction(1).
+	// Fatal returns to its caller if OnFatal returns
+	OnFatal FatalAction
+
+	// ErrorHandler is called when writing to Output fails, default reports
+	// to stderr at most once per minute for the same error. It is called
+	// after the write lock is released, so it may log through the same
+	// logger, and concurrently when logging from several goroutines
+	ErrorHandler ErrorHandler
+
+	// CallerSkip is the number of extra frames to skip above the first
+	// frame outside of gologger, used by wrappers around Logger
+	CallerSkip int
+
+	// StacktraceLevel attaches a stack trace to entries at this level or
+	// more severe, OFF disables it. PANIC entries always have a stack trace
+	StacktraceLevel Level
+
+	// fields are added to every entry, never modified once set
+	fields Fields
+
+	// root owns the mutex and counters shared by child loggers
+	root     *Logger
+	counters counters
 }
 
 // New creates a new Logger
@@ -320,6 +378,38 @@ func New() *Logger {
 	}
 }
 
+// AddCallerSkip returns a child Logger which skips n more frames when reporting caller
+func (l *Logger) AddCallerSkip(n int) *Logger {
+	c := l.clone()
+	c.CallerSkip += n
+	return c
+}
+
+// clone returns a child Logger sharing the output lock with l
+func (l *Logger) clone() *Logger {
+	return &Logger{
+		Level:           l.Level,
+		Format:          l.Format,
+		Output:          l.Output,
+		Sinks:           l.Sinks,
+		Redaction:       l.Redaction,
+		OnFatal:         l.OnFatal,
+		ErrorHandler:    l.ErrorHandler,
+		CallerSkip:      l.CallerSkip,
+		StacktraceLevel: l.StacktraceLevel,
+		fields:          l.fields,
+		root:            l.lockOwner(),
+	}
+}
+
+// lockOwner returns the Logger whose mutex guards Output and owns counters
+func (l *Logger) lockOwner() *Logger {
+	if l.root != nil {
+		return l.root
+	}
+	return l
+}
+
 // IsDebugEnabled indicates whether output message
 func (l *Logger) IsDebugEnabled() bool {
 	return l.Level >= DEBUG
@@ -360,169 +450,263 @@ func (l *Logger) IsDisabled() bool {
 	return l.Level <= OFF
 }
 
+// Log outputs message at level without calling panic() or os.Exit(1), Arguments are handled by fmt.Sprint
+func (l *Logger) Log(level Level, obj ...any) {
+	if level != OFF && l.Level >= level {
+		l.log(level, fmt.Sprint(redactArgs(obj)...))
+	}
+}
+
 // Debug outputs message, Arguments are handled by fmt.Sprint
 func (l *Logger) Debug(obj ...any) {
 	if l.Level >= DEBUG {
-		l.log(DEBUG, fmt.Sprint(obj...))
+		l.log(DEBUG, fmt.Sprint(redactArgs(obj)...))
 	}
 }
 
 // Info outputs message, Arguments are handled by fmt.Sprint
 func (l *Logger) Info(obj ...any) {
 	if l.Level >= INFO {
-		l.log(INFO, fmt.Sprint(obj...))
+		l.log(INFO, fmt.Sprint(redactArgs(obj)...))
 	}
 }
 
 // Print outputs message, Arguments are handled by fmt.Sprint
 func (l *Logger) Print(obj ...any) {
 	if l.Level != OFF {
-		l.log(INFO, fmt.Sprint(obj...))
+		l.log(INFO, fmt.Sprint(redactArgs(obj)...))
 	}
 }
 
 // Warn outputs message, Arguments are handled by fmt.Sprint
 func (l *Logger) Warn(obj ...any) {
 	if l.Level >= WARN {
-		l.log(WARN, fmt.Sprint(obj...))
+		l.log(WARN, fmt.Sprint(redactArgs(obj)...))
 	}
 }
 
 // Error outputs message, Arguments are handled by fmt.Sprint
 func (l *Logger) Error(obj ...any) {
 	if l.Level >= ERROR {
-		l.log(ERROR, fmt.Sprint(obj...))
+		l.log(ERROR, fmt.Sprint(redactArgs(obj)...))
 	}
 }
 
-// Panic outputs message, and followed by a call to panic() Arguments are handled by fmt.Sprint
+// Panic outputs message, and followed by a call to panic() with a *PanicError Arguments are handled by fmt.Sprint
 func (l *Logger) Panic(obj ...any) {
-	if l.Level >= PANIC {
-		l.log(PANIC, fmt.Sprint(obj...))
-	}
-	panic(fmt.Sprint(obj...))
+	l.panic(fmt.Sprint(redactArgs(obj)...))
 }
 
-// Fatal outputs message and followed by a call to os.Exit(1), Arguments are handled by fmt.Sprint
+// Fatal outputs message and followed by a call to OnFatal, os.Exit(1) by default, Arguments are handled by fmt.Sprint
 func (l *Logger) Fatal(obj ...any) {
-	if l.Level >= FATAL {
-		l.log(FATAL, fmt.Sprint(obj...))
-	}
-	Exit(1)
+	l.fatal(fmt.Sprint(redactArgs(obj)...))
 }
 
 // Debugln outputs message, Arguments are handled by fmt.Sprintln
 func (l *Logger) Debugln(obj ...any) {
 	if l.Level >= DEBUG {
-		l.log(DEBUG, vsprintln(obj...))
+		l.log(DEBUG, vsprintln(redactArgs(obj)...))
 	}
 }
 
 // Infoln outputs message, Arguments are handled by fmt.Sprintln
 func (l *Logger) Infoln(obj ...any) {
 	if l.Level >= INFO {
-		l.log(INFO, vsprintln(obj...))
+		l.log(INFO, vsprintln(redactArgs(obj)...))
 	}
 }
 
 // Println outputs message, Arguments are handled by fmt.Sprintln
 func (l *Logger) Println(obj ...any) {
 	if l.Level != OFF {
-		l.log(INFO, vsprintln(obj...))
+		l.log(INFO, vsprintln(redactArgs(obj)...))
 	}
 }
 
 // Warnln outputs message, Arguments are handled by fmt.Sprintln
 func (l *Logger) Warnln(obj ...any) {
 	if l.Level >= WARN {
-		l.log(WARN, vsprintln(obj...))
+		l.log(WARN, vsprintln(redactArgs(obj)...))
 	}
 }
 
 // Errorln outputs message, Arguments are handled by fmt.Sprintln
 func (l *Logger) Errorln(obj ...any) {
 	if l.Level >= ERROR {
-		l.log(ERROR, vsprintln(obj...))
+		l.log(ERROR, vsprintln(redactArgs(obj)...))
 	}
 }
 
-// Panicln outputs message and followed by a call to panic(), Arguments are handled by fmt.Sprintln
+// Panicln outputs message and followed by a call to panic() with a *PanicError, Arguments are handled by fmt.Sprintln
 func (l *Logger) Panicln(obj ...any) {
-	if l.Level >= PANIC {
-		l.log(PANIC, vsprintln(obj...))
-	}
-	panic(vsprintln(obj...))
+	l.panic(vsprintln(redactArgs(obj)...))
 }
 
-// Fatalln outputs message and followed by a call to os.Exit(1), Arguments are handled by fmt.Sprintln
+// Fatalln outputs message and followed by a call to OnFatal, os.Exit(1) by default, Arguments are handled by fmt.Sprintln
 func (l *Logger) Fatalln(obj ...any) {
-	if l.Level >= FATAL {
-		l.log(FATAL, vsprintln(obj...))
-	}
-	Exit(1)
+	l.fatal(vsprintln(redactArgs(obj)...))
 }
 
 // Debugf outputs message, Arguments are handles by fmt.Sprintf
 func (l *Logger) Debugf(msg string, args ...any) {
 	if l.Level >= DEBUG {
-		l.log(DEBUG, fmt.Sprintf(msg, args...))
+		l.log(DEBUG, fmt.Sprintf(msg, redactArgs(args)...))
 	}
 }
 
 // Infof outputs message, Arguments are handles by fmt.Sprintf
 func (l *Logger) Infof(msg string, args ...any) {
 	if l.Level >= INFO {
-		l.log(INFO, fmt.Sprintf(msg, args...))
+		l.log(INFO, fmt.Sprintf(msg, redactArgs(args)...))
 	}
 }
 
 // Printf outputs message, Arguments are handles by fmt.Sprintf
 func (l *Logger) Printf(msg string, args ...any) {
 	if l.Level != OFF {
-		l.log(INFO, fmt.Sprintf(msg, args...))
+		l.log(INFO, fmt.Sprintf(msg, redactArgs(args)...))
 	}
 }
 
 // Warnf outputs message, Arguments are handles by fmt.Sprintf
 func (l *Logger) Warnf(msg string, args ...any) {
 	if l.Level >= WARN {
-		l.log(WARN, fmt.Sprintf(msg, args...))
+		l.log(WARN, fmt.Sprintf(msg, redactArgs(args)...))
 	}
 }
 
 // Errorf outputs message, Arguments are handles by fmt.Sprintf
 func (l *Logger) Errorf(msg string, args ...any) {
 	if l.Level >= ERROR {
-		l.log(ERROR, fmt.Sprintf(msg, args...))
+		l.log(ERROR, fmt.Sprintf(msg, redactArgs(args)...))
 	}
 }
 
-// Panicf outputs message and followed by a call to panic(), Arguments are handles by fmt.Sprintf
+// Panicf outputs message and followed by a call to panic() with a *PanicError, Arguments are handles by fmt.Sprintf
 func (l *Logger) Panicf(msg string, args ...any) {
+	l.panic(fmt.Sprintf(msg, redactArgs(args)...))
+}
+
+// Fatalf outputs message and followed by a call to OnFatal, os.Exit(1) by default, Arguments are handles by fmt.Sprintf
+func (l *Logger) Fatalf(msg string, args ...any) {
+	l.fatal(fmt.Sprintf(msg, redactArgs(args)...))
+}
+
+func (l *Logger) log(level Level, msg string) {
+	l.output(l.newEntry(level, msg))
+}
+
+// panic outputs msg if enabled and panics with a *PanicError
+func (l *Logger) panic(msg string) {
+	entry := l.newEntry(PANIC, msg)
 	if l.Level >= PANIC {
-		l.log(PANIC, fmt.Sprintf(msg, args...))
+		l.output(entry)
 	}
-	panic(fmt.Sprintf(msg, args...))
+	panic(&PanicError{Entry: entry})
 }
 
-// Fatalf outputs message and followed by a call to os.Exit(1), Arguments are handles by fmt.Sprintf
-func (l *Logger) Fatalf(msg string, args ...any) {
+// fatal outputs msg if enabled and runs OnFatal
+func (l *Logger) fatal(msg string) {
+	entry := l.newEntry(FATAL, msg)
 	if l.Level >= FATAL {
-		l.log(FATAL, fmt.Sprintf(msg, args...))
+		l.output(entry)
+	}
+	if l.OnFatal != nil {
+		l.OnFatal(entry)
+	} else {
+		ExitAction(1)(entry)
 	}
-	Exit(1)
 }
 
-func (l *Logger) log(level Level, msg string) {
-	line := l.Format.Format(level, msg, l)
+func (l *Logger) newEntry(level Level, msg string) *Entry {
+	withStack := level == PANIC || (level != OFF && level <= l.StacktraceLevel)
+	caller, stack := l.capture(withStack)
+	msg, fields := l.redact(msg, l.fields)
+
+	return &Entry{
+		Logger:  l,
+		Time:    time.Now(),
+		Level:   level,
+		Message: msg,
+		Fields:  fields,
+		Caller:  caller,
+		Stack:   stack,
+	}
+}
+
+// output formats entry and writes it to Output or Sinks
+func (l *Logger) output(entry *Entry) {
+	level := entry.Level
+	l.lockOwner().counters.countLine(level)
+
+	if len(l.Sinks) == 0 {
+		entry.Output = l.Output
+		line := l.Format.Format(entry)
+
+		mutex := &l.lockOwner().mutex
+		mutex.Lock()
+		err := l.write(entry, l.Output, line)
+		if err != nil {
+			l.lockOwner().counters.dropped.Add(1)
+		}
+		mutex.Unlock()
+
+		// without the lock, so the handler may log through l
+		if err != nil {
+			l.handleError(l.Output, err)
+		}
+		return
+	}
+
+	lines := make([][]byte, len(l.Sinks))
+	for i, sink := range l.Sinks {
+		if sink.Enabled(level) {
+			entry.Output = sink.Output
+			lines[i] = sink.Format.Format(entry)
+		}
+	}
 
-	l.mutex.Lock()
-	defer l.mutex.Unlock()
+	mutex := &l.lockOwner().mutex
+	mutex.Lock()
+	errs := make([]error, len(l.Sinks))
+	attempted, written := false, false
+	for i, sink := range l.Sinks {
+		if lines[i] != nil {
+			attempted = true
+			entry.Output = sink.Output
+			if errs[i] = l.write(entry, sink.Output, lines[i]); errs[i] == nil {
+				written = true
+			}
+		}
+	}
+	if attempted && !written {
+		l.lockOwner().counters.dropped.Add(1)
+	}
+	mutex.Unlock()
+
+	for i, err := range errs {
+		if err != nil {
+			l.handleError(l.Sinks[i].Output, err)
+		}
+	}
+}
+
+// write outputs line and updates counters, must be called with lock held
+func (l *Logger) write(entry *Entry, output io.Writer, line []byte) error {
+	var n int
+	var err error
+	if w, ok := output.(EntryWriter); ok {
+		n, err = w.WriteEntry(entry, line)
+	} else {
+		n, err = output.Write(line)
+	}
 
-	_, err := l.Output.Write(line)
+	c := &l.lockOwner().counters
+	c.bytesWritten.Add(uint64(n))
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "failed to write log, %v\n", err)
+		c.writeErrors.Add(1)
 	}
+	return err
 }
 
 // vsprintln => spaces are always added between operands
@@ -1,9 +1,13 @@
 package gologger_test
 
 import (
-	"io"
+	"bytes"
+	"errors"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/fun-think/gologger"
 	"github.com/fun-think/gologger/format"
@@ -11,17 +15,273 @@ import (
 )
 
 func TestGologger(t *testing.T) {
-	Logger := &gologger.Logger{
-		Level:  gologger.DEBUG,
-		Format: new(format.TextFormat),
-		Output: io.MultiWriter(
-			&writer.DailyFileWriter{
-				Name:     "cache/logs",
+	dir := t.TempDir()
+	Logger := gologger.NewTee(
+		&gologger.Sink{
+			Level:  gologger.DEBUG,
+			Format: new(format.TextFormat),
+			Output: os.Stdout,
+		},
+		&gologger.Sink{
+			Level:  gologger.DEBUG,
+			Format: new(format.JSONFormat),
+			Output: &writer.DailyFileWriter{
+				Name:     dir,
 				MaxCount: 7,
 			},
-			os.Stdout,
-		),
-	}
+		},
+	)
 	Logger.Error("err")
-	t.Fatal("err")
+
+	name := filepath.Join(dir, time.Now().Format("20060102")+".log")
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte("{")) || !bytes.Contains(data, []byte(`"msg":"err"`)) {
+		t.Errorf("unexpected daily file content: %s", data)
+	}
+}
+
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestTee(t *testing.T) {
+	var all, errs bytes.Buffer
+	logger := gologger.NewTee(
+		&gologger.Sink{Level: gologger.DEBUG, Format: &format.TextFormat{AppName: "app"}, Output: failWriter{}},
+		&gologger.Sink{Level: gologger.INFO, Format: &format.TextFormat{AppName: "app"}, Output: &all},
+		&gologger.Sink{Level: gologger.ERROR, Format: new(format.JSONFormat), Output: &errs},
+	)
+	if logger.Level != gologger.DEBUG {
+		t.Errorf("tee level = %v, want DEBUG", logger.Level)
+	}
+
+	logger.Debug("debug")
+	logger.Info("info")
+	logger.Error("error")
+
+	if got := strings.Count(all.String(), "\n"); got != 2 {
+		t.Errorf("INFO sink got %d lines:\n%s", got, all.String())
+	}
+	if strings.Contains(all.String(), "debug") {
+		t.Errorf("INFO sink got DEBUG entry:\n%s", all.String())
+	}
+	if got := strings.Count(errs.String(), "\n"); got != 1 || !strings.Contains(errs.String(), `"level":"ERROR"`) {
+		t.Errorf("ERROR sink got:\n%s", errs.String())
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	var handled []error
+	logger := &gologger.Logger{
+		Level:        gologger.INFO,
+		Format:       new(format.JSONFormat),
+		Output:       failWriter{},
+		ErrorHandler: func(err error) { handled = append(handled, err) },
+	}
+	logger.Info("a")
+	logger.AddCallerSkip(1).Info("b")
+
+	if len(handled) != 2 || logger.FailedWrites() != 2 {
+		t.Fatalf("handled %d errors, counted %d", len(handled), logger.FailedWrites())
+	}
+	var werr *gologger.WriteError
+	if !errors.As(handled[0], &werr) {
+		t.Errorf("handled %T, want *WriteError", handled[0])
+	}
+}
+
+// failOnceWriter fails the first write
+type failOnceWriter struct {
+	bytes.Buffer
+	failed bool
+}
+
+func (w *failOnceWriter) Write(p []byte) (int, error) {
+	if !w.failed {
+		w.failed = true
+		return 0, errors.New("disk full")
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestErrorHandlerLogs(t *testing.T) {
+	out := new(failOnceWriter)
+	logger := &gologger.Logger{Level: gologger.INFO, Format: &format.TextFormat{}, Output: out}
+	logger.ErrorHandler = func(err error) { logger.Warnf("logging failed, %v", err) }
+
+	// the handler logs through the logger without deadlocking
+	logger.Info("lost")
+	if got := out.String(); !strings.Contains(got, "logging failed, failed to write log, disk full") {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestErrorReporter(t *testing.T) {
+	var out bytes.Buffer
+	reporter := &gologger.ErrorReporter{Output: &out, Interval: time.Hour}
+	for i := 0; i < 5; i++ {
+		reporter.Report(errors.New("disk full"))
+	}
+	reporter.Report(errors.New("other"))
+
+	if got := out.String(); got != "disk full\nother\n" {
+		t.Errorf("reported %q", got)
+	}
+}
+
+func TestErrorReporterExpiry(t *testing.T) {
+	var out bytes.Buffer
+	reporter := &gologger.ErrorReporter{Output: &out, Interval: 10 * time.Millisecond}
+	reporter.Report(errors.New("disk full"))
+	reporter.Report(errors.New("disk full"))
+	time.Sleep(20 * time.Millisecond)
+
+	// the suppressed count is reported once the message expired
+	reporter.Report(errors.New("other"))
+	reporter.Report(errors.New("disk full"))
+	if got := out.String(); got != "disk full\ndisk full (suppressed 1 times)\nother\ndisk full\n" {
+		t.Errorf("reported %q", got)
+	}
+}
+
+func TestOnFatal(t *testing.T) {
+	var buf bytes.Buffer
+	var fatal *gologger.Entry
+	logger := &gologger.Logger{
+		Level:   gologger.INFO,
+		Format:  &format.TextFormat{AppName: "app"},
+		Output:  &buf,
+		OnFatal: func(entry *gologger.Entry) { fatal = entry },
+	}
+	logger.Fatalf("code %d", 7)
+	if fatal == nil || fatal.Message != "code 7" || !strings.Contains(buf.String(), "FATAL") {
+		t.Errorf("OnFatal got %+v, output %q", fatal, buf.String())
+	}
+
+	logger.OnFatal = gologger.NoopAction
+	logger.Fatal("returns")
+
+	ran := false
+	t.Cleanup(gologger.RegisterExitHandler(func() { ran = true }))
+	saved := gologger.Exit
+	defer func() { gologger.Exit = saved }()
+	code := 0
+	gologger.Exit = func(c int) { code = c }
+	logger.OnFatal = gologger.ExitAction(3)
+	logger.Fatal("exit")
+	if !ran || code != 3 {
+		t.Errorf("exit handler ran %v, code %d", ran, code)
+	}
+
+	unregister := gologger.RegisterExitHandler(func() { t.Error("unregistered handler ran") })
+	unregister()
+	unregister()
+	gologger.RunExitHandlers()
+}
+
+func TestPanicError(t *testing.T) {
+	logger := &gologger.Logger{Level: gologger.OFF, Format: new(format.JSONFormat), Output: &bytes.Buffer{}}
+	defer func() {
+		err, ok := recover().(*gologger.PanicError)
+		if !ok || err.Error() != "bad input" || err.Entry.Level != gologger.PANIC || len(err.Entry.Stack) == 0 {
+			t.Errorf("unexpected panic value %#v", err)
+		}
+	}()
+	logger.Panicln("bad", "input")
+}
+
+type card string
+
+func (c card) Redact() any {
+	return "****" + string(c[len(c)-4:])
+}
+
+func TestRedaction(t *testing.T) {
+	type request struct {
+		Username string
+		Password string
+	}
+
+	for _, f := range []gologger.Format{&format.TextFormat{AppName: "app"}, new(format.JSONFormat)} {
+		var buf bytes.Buffer
+		logger := &gologger.Logger{Level: gologger.INFO, Format: f, Output: &buf, Redaction: gologger.DefaultRedaction()}
+
+		logger.Infof("%+v", request{Username: "bob", Password: "hunter2"})
+		logger.Info(`{"token": "abc123"} Authorization: Bearer eyJhbGciOi.payload`)
+		logger.Info("mail bob@example.com card 4111 1111 1111 1111")
+		logger.WithFields(gologger.Fields{"password": "x", "Card": card("4111111111111111"), "note": "to bob@example.com"}).Info("fields")
+
+		out := buf.String()
+		for _, secret := range []string{"hunter2", "abc123", "eyJhbGciOi", "bob@example.com", "4111 1111", "4111111111111111"} {
+			if strings.Contains(out, secret) {
+				t.Errorf("%T: %q not redacted:\n%s", f, secret, out)
+			}
+		}
+		for _, kept := range []string{"Username:bob", "****1111", "[REDACTED]"} {
+			if !strings.Contains(out, kept) {
+				t.Errorf("%T: %q missing:\n%s", f, kept, out)
+			}
+		}
+	}
+}
+
+func TestRedactionKeysAndCards(t *testing.T) {
+	r := gologger.DefaultRedaction()
+	for msg, want := range map[string]string{
+		"access_token=abc refresh-token: def":     "access_token=[REDACTED] refresh-token: [REDACTED]",
+		`{"access_token":"abc"}`:                  `{"access_token":[REDACTED]}`,
+		"tokens=3 mytoken=abc":                    "tokens=3 mytoken=abc",
+		"card 4111-1111-1111-1111":                "card [REDACTED]",
+		"order 1234567890123456 at 1700000000000": "order 1234567890123456 at 1700000000000",
+	} {
+		if got := r.Message(msg); got != want {
+			t.Errorf("%s: got %s, want %s", msg, got, want)
+		}
+	}
+
+	fields := r.Fields(gologger.Fields{"access_token": "abc", "Client-Secret": "def", "tokens": 3})
+	if fields["access_token"] != "[REDACTED]" || fields["Client-Secret"] != "[REDACTED]" || fields["tokens"] != 3 {
+		t.Errorf("got %v", fields)
+	}
+}
+
+func TestRedactorArgs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := &gologger.Logger{Level: gologger.INFO, Format: new(format.JSONFormat), Output: &buf}
+	logger.Info("paid with ", card("4111111111111111"))
+	if !strings.Contains(buf.String(), `"msg":"paid with ****1111"`) {
+		t.Errorf("Redactor argument not redacted: %s", buf.String())
+	}
+}
+
+func TestStatsLevels(t *testing.T) {
+	var buf bytes.Buffer
+	logger := &gologger.Logger{Level: gologger.DEBUG + 2, Format: &format.TextFormat{}, Output: &buf}
+	logger.Info("info")
+	logger.Log(gologger.DEBUG+1, "custom")
+
+	stats := logger.Stats()
+	if stats.Lines[gologger.INFO] != 1 || strings.Count(buf.String(), "\n") != 2 {
+		t.Errorf("counted %v for %q", stats.Lines, buf.String())
+	}
+}
+
+type legacyFormat struct{}
+
+func (legacyFormat) Format(level gologger.Level, msg string, logger *gologger.Logger) []byte {
+	return []byte(level.String() + " " + msg + "\n")
+}
+
+func TestAdaptLegacyFormat(t *testing.T) {
+	var buf bytes.Buffer
+	logger := &gologger.Logger{Level: gologger.INFO, Format: gologger.AdaptLegacyFormat(legacyFormat{}), Output: &buf}
+	logger.Info("old")
+	if buf.String() != "INFO old\n" {
+		t.Errorf("got %q", buf.String())
+	}
 }
@@ -0,0 +1,73 @@
+package gologgertest_test
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/fun-think/gologger"
+	"github.com/fun-think/gologger/gologgertest"
+)
+
+func TestObserver(t *testing.T) {
+	logger, logs := gologgertest.NewObserver(gologger.INFO)
+	logger.Debug("hidden")
+	logger.Info("started")
+	logger.WithField("user", "bob").Errorf("failed %d times", 3)
+
+	if logs.Len() != 2 {
+		t.Fatalf("recorded %d entries, want 2", logs.Len())
+	}
+	errs := logs.FilterLevel(gologger.ERROR).All()
+	if len(errs) != 1 || errs[0].Message != "failed 3 times" || errs[0].Fields["user"] != "bob" {
+		t.Errorf("unexpected ERROR entries %+v", errs)
+	}
+	if !errs[0].Caller.Defined() {
+		t.Errorf("caller not recorded")
+	}
+	if logs.FilterMessage("started").Len() != 1 || logs.FilterField("user", "bob").Len() != 1 {
+		t.Errorf("filters do not match")
+	}
+	if len(logs.TakeAll()) != 2 || logs.Len() != 0 {
+		t.Errorf("TakeAll should clear entries")
+	}
+}
+
+func TestWithExit(t *testing.T) {
+	logger, logs := gologgertest.NewObserver(gologger.INFO)
+	reached := false
+	stub := gologgertest.WithExit(func() {
+		logger.Fatal("fatal")
+		reached = true
+	})
+	if !stub.Exited || stub.Code != 1 || reached {
+		t.Errorf("got %+v, reached %v", stub, reached)
+	}
+	if logs.FilterLevel(gologger.FATAL).Len() != 1 {
+		t.Errorf("fatal entry not recorded")
+	}
+
+	value, panicked := gologgertest.WithPanic(func() {
+		logger.Panicf("bad %s", "thing")
+	})
+	if !panicked || value == nil {
+		t.Errorf("Panicf did not panic")
+	}
+}
+
+func TestWithExitPanic(t *testing.T) {
+	value, panicked := gologgertest.WithPanic(func() {
+		gologgertest.WithExit(func() { panic("boom") })
+	})
+	if !panicked || value != "boom" {
+		t.Errorf("got %v, %v", value, panicked)
+	}
+
+	// Goexit is not a panic, the goroutine still exits
+	done := make(chan bool)
+	go func() {
+		defer func() { done <- true }()
+		gologgertest.WithPanic(runtime.Goexit)
+		t.Error("Goexit returned")
+	}()
+	<-done
+}
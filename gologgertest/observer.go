@@ -0,0 +1,136 @@
+// Package gologgertest provides helpers to assert on log output in tests
+package gologgertest
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fun-think/gologger"
+)
+
+// LoggedEntry is an entry recorded by ObservedLogs
+type LoggedEntry struct {
+	Time    time.Time
+	Level   gologger.Level
+	Message string
+	Fields  gologger.Fields
+	Caller  gologger.Caller
+	Stack   gologger.Stack
+}
+
+// ObservedLogs is a concurrency safe collection of recorded entries
+type ObservedLogs struct {
+	mutex   sync.Mutex
+	entries []LoggedEntry
+}
+
+// NewObserver returns a Logger at level recording entries to the returned ObservedLogs
+func NewObserver(level gologger.Level) (*gologger.Logger, *ObservedLogs) {
+	logs := new(ObservedLogs)
+	return &gologger.Logger{
+		Level:  level,
+		Format: messageFormat{},
+		Output: logs,
+	}, logs
+}
+
+// messageFormat outputs the message only, entries are recorded by WriteEntry
+type messageFormat struct{}
+
+func (messageFormat) Format(entry *gologger.Entry) []byte {
+	return []byte(entry.Message + "\n")
+}
+
+// Write implements io.Writer, lines written without an entry are recorded as INFO
+func (o *ObservedLogs) Write(p []byte) (n int, err error) {
+	o.add(LoggedEntry{
+		Time:    time.Now(),
+		Level:   gologger.INFO,
+		Message: strings.TrimSuffix(string(p), "\n"),
+	})
+	return len(p), nil
+}
+
+// WriteEntry implements gologger.EntryWriter
+func (o *ObservedLogs) WriteEntry(entry *gologger.Entry, p []byte) (n int, err error) {
+	o.add(LoggedEntry{
+		Time:    entry.Time,
+		Level:   entry.Level,
+		Message: entry.Message,
+		Fields:  entry.Fields,
+		Caller:  entry.Caller,
+		Stack:   entry.Stack,
+	})
+	return len(p), nil
+}
+
+func (o *ObservedLogs) add(entry LoggedEntry) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.entries = append(o.entries, entry)
+}
+
+// Len returns the number of recorded entries
+func (o *ObservedLogs) Len() int {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return len(o.entries)
+}
+
+// All returns a copy of recorded entries
+func (o *ObservedLogs) All() []LoggedEntry {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return append([]LoggedEntry(nil), o.entries...)
+}
+
+// TakeAll returns recorded entries and clears them
+func (o *ObservedLogs) TakeAll() []LoggedEntry {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	entries := o.entries
+	o.entries = nil
+	return entries
+}
+
+// Filter returns entries for which keep returns true
+func (o *ObservedLogs) Filter(keep func(LoggedEntry) bool) *ObservedLogs {
+	filtered := new(ObservedLogs)
+	for _, entry := range o.All() {
+		if keep(entry) {
+			filtered.entries = append(filtered.entries, entry)
+		}
+	}
+	return filtered
+}
+
+// FilterLevel returns entries logged at level
+func (o *ObservedLogs) FilterLevel(level gologger.Level) *ObservedLogs {
+	return o.Filter(func(e LoggedEntry) bool {
+		return e.Level == level
+	})
+}
+
+// FilterMessage returns entries with message msg
+func (o *ObservedLogs) FilterMessage(msg string) *ObservedLogs {
+	return o.Filter(func(e LoggedEntry) bool {
+		return e.Message == msg
+	})
+}
+
+// FilterMessageSnippet returns entries whose message contains snippet
+func (o *ObservedLogs) FilterMessageSnippet(snippet string) *ObservedLogs {
+	return o.Filter(func(e LoggedEntry) bool {
+		return strings.Contains(e.Message, snippet)
+	})
+}
+
+// FilterField returns entries having field key with value
+func (o *ObservedLogs) FilterField(key string, value any) *ObservedLogs {
+	return o.Filter(func(e LoggedEntry) bool {
+		v, ok := e.Fields[key]
+		return ok && reflect.DeepEqual(v, value)
+	})
+}
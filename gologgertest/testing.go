@@ -0,0 +1,92 @@
+package gologgertest
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/fun-think/gologger"
+	"github.com/fun-think/gologger/format"
+)
+
+// TBWriter writes lines with t.Log, so they are shown only when the test fails or with -v
+type TBWriter struct {
+	TB testing.TB
+}
+
+// Write implements io.Writer
+func (w TBWriter) Write(p []byte) (n int, err error) {
+	w.TB.Helper()
+	w.TB.Log(strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
+
+// NewLogger returns a DEBUG Logger writing text lines to t.Log
+func NewLogger(t testing.TB) *gologger.Logger {
+	return &gologger.Logger{
+		Level:  gologger.DEBUG,
+		Format: new(format.TextFormat),
+		Output: TBWriter{TB: t},
+	}
+}
+
+// ExitStub is the result of WithExit
+type ExitStub struct {
+	Exited bool
+	Code   int
+}
+
+// exitMutex serializes replacing gologger.Exit
+var exitMutex sync.Mutex
+
+// WithExit runs f with gologger.Exit stubbed. A call to Exit stops f like
+// os.Exit would, and its code is returned. A panic in f is raised again in
+// the caller
+func WithExit(f func()) ExitStub {
+	exitMutex.Lock()
+	defer exitMutex.Unlock()
+
+	saved := gologger.Exit
+	defer func() { gologger.Exit = saved }()
+
+	var stub ExitStub
+	done := make(chan struct{})
+	gologger.Exit = func(code int) {
+		stub = ExitStub{Exited: true, Code: code}
+		runtime.Goexit()
+	}
+
+	// f runs in its own goroutine, so Exit can stop it with Goexit
+	var value any
+	var panicked bool
+	go func() {
+		defer close(done)
+		value, panicked = WithPanic(f)
+	}()
+	<-done
+
+	if panicked {
+		panic(value)
+	}
+	return stub
+}
+
+// WithPanic runs f and returns the value passed to panic, if f panicked.
+// runtime.Goexit in f, like t.FailNow, is not a panic and is not stopped
+func WithPanic(f func()) (value any, panicked bool) {
+	returned := false
+	func() {
+		defer func() {
+			if !returned {
+				// nil while running Goexit, which continues
+				value = recover()
+			}
+		}()
+		f()
+		returned = true
+	}()
+
+	// only reached when f returned or panicked
+	return value, !returned
+}
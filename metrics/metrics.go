@@ -0,0 +1,190 @@
+// Package metrics exports gologger and writer stats via expvar and
+// in Prometheus text format
+package metrics
+
+import (
+	"bufio"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fun-think/gologger"
+	"github.com/fun-think/gologger/writer"
+)
+
+// StatsWriter is implemented by writers of the writer package
+type StatsWriter interface {
+	Stats() writer.Stats
+}
+
+// Registry collects named loggers and writers to export their stats
+type Registry struct {
+	mutex   sync.Mutex
+	loggers map[string]*gologger.Logger
+	writers map[string]StatsWriter
+}
+
+// AddLogger registers logger under name
+func (r *Registry) AddLogger(name string, logger *gologger.Logger) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.loggers == nil {
+		r.loggers = make(map[string]*gologger.Logger)
+	}
+	r.loggers[name] = logger
+}
+
+// AddWriter registers w under name
+func (r *Registry) AddWriter(name string, w StatsWriter) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.writers == nil {
+		r.writers = make(map[string]StatsWriter)
+	}
+	r.writers[name] = w
+}
+
+// Var returns an expvar.Var rendering all stats as json
+func (r *Registry) Var() expvar.Var {
+	return expvar.Func(func() any {
+		loggers, writers := r.snapshot()
+
+		data := map[string]any{}
+		for name, stats := range loggers {
+			lines := make(map[string]uint64, len(stats.Lines))
+			for level, n := range stats.Lines {
+				lines[level.String()] = n
+			}
+			data["logger."+name] = map[string]any{
+				"lines":         lines,
+				"bytes_written": stats.BytesWritten,
+				"write_errors":  stats.WriteErrors,
+				"dropped":       stats.Dropped,
+			}
+		}
+		for name, stats := range writers {
+			data["writer."+name] = map[string]any{
+				"rotations":     stats.Rotations,
+				"files_deleted": stats.FilesDeleted,
+				"current_size":  stats.CurrentSize,
+			}
+		}
+		return data
+	})
+}
+
+// Publish publishes stats with expvar under name, it panics if name is used
+func (r *Registry) Publish(name string) {
+	expvar.Publish(name, r.Var())
+}
+
+// ServeHTTP implements http.Handler, rendering stats in Prometheus text format
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteTo(w)
+}
+
+// WriteTo writes stats in Prometheus text format
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	loggers, writers := r.snapshot()
+	cw := &countWriter{w: bufio.NewWriter(w)}
+
+	loggerNames := sortedKeys(loggers)
+	writerNames := sortedKeys(writers)
+
+	header(cw, "gologger_lines_total", "counter", "Log entries by level.")
+	for _, name := range loggerNames {
+		stats := loggers[name]
+		for level := gologger.FATAL; level <= gologger.DEBUG; level++ {
+			fmt.Fprintf(cw, "gologger_lines_total{logger=%s,level=%s} %d\n", quote(name), quote(level.String()), stats.Lines[level])
+		}
+	}
+	header(cw, "gologger_bytes_written_total", "counter", "Bytes written to outputs.")
+	for _, name := range loggerNames {
+		fmt.Fprintf(cw, "gologger_bytes_written_total{logger=%s} %d\n", quote(name), loggers[name].BytesWritten)
+	}
+	header(cw, "gologger_write_errors_total", "counter", "Failed writes to outputs.")
+	for _, name := range loggerNames {
+		fmt.Fprintf(cw, "gologger_write_errors_total{logger=%s} %d\n", quote(name), loggers[name].WriteErrors)
+	}
+	header(cw, "gologger_dropped_total", "counter", "Entries not written to any output.")
+	for _, name := range loggerNames {
+		fmt.Fprintf(cw, "gologger_dropped_total{logger=%s} %d\n", quote(name), loggers[name].Dropped)
+	}
+
+	header(cw, "gologger_writer_rotations_total", "counter", "Log file rotations.")
+	for _, name := range writerNames {
+		fmt.Fprintf(cw, "gologger_writer_rotations_total{writer=%s} %d\n", quote(name), writers[name].Rotations)
+	}
+	header(cw, "gologger_writer_files_deleted_total", "counter", "Old log files deleted.")
+	for _, name := range writerNames {
+		fmt.Fprintf(cw, "gologger_writer_files_deleted_total{writer=%s} %d\n", quote(name), writers[name].FilesDeleted)
+	}
+	header(cw, "gologger_writer_file_size_bytes", "gauge", "Size of the current log file.")
+	for _, name := range writerNames {
+		fmt.Fprintf(cw, "gologger_writer_file_size_bytes{writer=%s} %d\n", quote(name), writers[name].CurrentSize)
+	}
+
+	if cw.err != nil {
+		return cw.n, cw.err
+	}
+	return cw.n, cw.w.Flush()
+}
+
+func (r *Registry) snapshot() (map[string]gologger.Stats, map[string]writer.Stats) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	loggers := make(map[string]gologger.Stats, len(r.loggers))
+	for name, logger := range r.loggers {
+		loggers[name] = logger.Stats()
+	}
+	writers := make(map[string]writer.Stats, len(r.writers))
+	for name, w := range r.writers {
+		writers[name] = w.Stats()
+	}
+	return loggers, writers
+}
+
+func header(w io.Writer, name, typ, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// quote returns a quoted label value
+func quote(s string) string {
+	return `"` + labelEscaper.Replace(s) + `"`
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// countWriter counts written bytes and keeps the first error
+type countWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	w.err = err
+	return n, err
+}
@@ -0,0 +1,46 @@
+package metrics_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fun-think/gologger"
+	"github.com/fun-think/gologger/metrics"
+	"github.com/fun-think/gologger/writer"
+)
+
+func TestRegistry(t *testing.T) {
+	logger := &gologger.Logger{Level: gologger.INFO, Format: gologger.New().Format, Output: io.Discard}
+	logger.Error("a")
+	logger.Info("b")
+	logger.Debug("c")
+
+	r := new(metrics.Registry)
+	r.AddLogger("app", logger)
+	r.AddWriter("file", new(writer.SizeFileWriter))
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`gologger_lines_total{logger="app",level="ERROR"} 1`,
+		`gologger_lines_total{logger="app",level="DEBUG"} 0`,
+		`# TYPE gologger_writer_file_size_bytes gauge`,
+		`gologger_writer_rotations_total{writer="file"} 0`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %q in:\n%s", want, body)
+		}
+	}
+
+	var data map[string]map[string]any
+	if err := json.Unmarshal([]byte(r.Var().String()), &data); err != nil {
+		t.Fatal(err)
+	}
+	if lines := data["logger.app"]["lines"].(map[string]any); lines["INFO"] != 1.0 {
+		t.Errorf("expvar lines = %v", lines)
+	}
+}
@@ -0,0 +1,231 @@
+// Package middleware provides net/http middleware logging through gologger
+package middleware
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fun-think/gologger"
+)
+
+// AccessFormat selects how requests are logged
+type AccessFormat int
+
+// These are the supported access log formats
+const (
+	// Structured logs a short message with request details as fields
+	Structured AccessFormat = iota
+	// Common logs the Apache common log format as message
+	Common
+	// Combined logs the Apache combined log format as message
+	Combined
+)
+
+// DefaultRequestIDHeader is the header used when AccessLog.RequestIDHeader is empty
+const DefaultRequestIDHeader = "X-Request-Id"
+
+// maxRequestIDLength is the longest request id accepted from a request
+const maxRequestIDLength = 128
+
+// AccessLog logs every request handled by the wrapped handler
+type AccessLog struct {
+	Logger          *gologger.Logger // default is gologger.Default
+	Format          AccessFormat
+	RequestIDHeader string // read and echoed request id header, default is DefaultRequestIDHeader
+
+	// Level returns the level for a response status,
+	// default is ERROR for 5xx, WARN for 4xx and INFO otherwise
+	Level func(status int) gologger.Level
+}
+
+// Wrap returns a handler logging requests served by next. The request
+// context carries a Logger with the request id, see gologger.FromContext.
+// A request id longer than 128 chars or with chars other than letters, digits
+// and "-._:" is replaced by a new one. A request whose handler panics is
+// logged with status 500 unless a status was sent, and the panic continues
+func (a *AccessLog) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		logger := a.Logger
+		if logger == nil {
+			logger = gologger.Default
+		}
+
+		header := a.RequestIDHeader
+		if header == "" {
+			header = DefaultRequestIDHeader
+		}
+		requestID := r.Header.Get(header)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		w.Header().Set(header, requestID)
+
+		logger = logger.WithField("request_id", requestID)
+		r = r.WithContext(gologger.NewContext(r.Context(), logger))
+
+		rw := &responseWriter{ResponseWriter: w}
+		completed := false
+		defer func() {
+			if rw.status == 0 {
+				if completed {
+					rw.status = http.StatusOK
+				} else {
+					rw.status = http.StatusInternalServerError
+				}
+			}
+			a.log(logger, r, rw, start, time.Since(start))
+		}()
+		next.ServeHTTP(rw, r)
+		completed = true
+	})
+}
+
+// validRequestID indicates whether id is not empty and safe to echo and log
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range []byte(id) {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+			c == '-' || c == '.' || c == '_' || c == ':') {
+			return false
+		}
+	}
+	return true
+}
+
+func (a *AccessLog) log(logger *gologger.Logger, r *http.Request, rw *responseWriter, start time.Time, duration time.Duration) {
+	level := levelByStatus(rw.status)
+	if a.Level != nil {
+		level = a.Level(rw.status)
+	}
+
+	switch a.Format {
+	case Common:
+		logger.Log(level, commonLog(r, rw, start))
+	case Combined:
+		logger.Log(level, fmt.Sprintf("%s %q %q", commonLog(r, rw, start), r.Referer(), r.UserAgent()))
+	default:
+		logger.WithFields(gologger.Fields{
+			"method":      r.Method,
+			"path":        r.URL.Path,
+			"status":      rw.status,
+			"bytes":       rw.bytes,
+			"duration_ms": float64(duration.Microseconds()) / 1000,
+			"remote_addr": r.RemoteAddr,
+			"user_agent":  r.UserAgent(),
+		}).Log(level, r.Method+" "+r.URL.Path+" "+strconv.Itoa(rw.status))
+	}
+}
+
+// commonLog returns request in Apache common log format
+func commonLog(r *http.Request, rw *responseWriter, start time.Time) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	user := "-"
+	if u, _, ok := r.BasicAuth(); ok && u != "" {
+		user = escapeField(u)
+	} else if r.URL.User != nil && r.URL.User.Username() != "" {
+		user = escapeField(r.URL.User.Username())
+	}
+	return fmt.Sprintf("%s - %s [%s] \"%s %s %s\" %d %d",
+		host, user, start.Format("02/Jan/2006:15:04:05 -0700"),
+		r.Method, escapeField(r.RequestURI), r.Proto, rw.status, rw.bytes)
+}
+
+// escapeField escapes spaces, quotes, backslashes and non-printable bytes
+// sent by the client as \xHH, so they can not split or forge log lines
+func escapeField(s string) string {
+	const hexDigits = "0123456789abcdef"
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c > ' ' && c < 0x7f && c != '"' && c != '\\' {
+			if b != nil {
+				b = append(b, c)
+			}
+			continue
+		}
+		if b == nil {
+			b = append(make([]byte, 0, len(s)+8), s[:i]...)
+		}
+		b = append(b, '\\', 'x', hexDigits[c>>4], hexDigits[c&0xf])
+	}
+	if b == nil {
+		return s
+	}
+	return string(b)
+}
+
+func levelByStatus(status int) gologger.Level {
+	switch {
+	case status >= 500:
+		return gologger.ERROR
+	case status >= 400:
+		return gologger.WARN
+	default:
+		return gologger.INFO
+	}
+}
+
+func newRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// responseWriter records status and size of the response
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	// informational responses like 103 Early Hints precede the final one
+	if w.status == 0 && status >= 200 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, fmt.Errorf("%T does not implement http.Hijacker", w.ResponseWriter)
+}
+
+// Unwrap returns the underlying http.ResponseWriter, used by http.ResponseController
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
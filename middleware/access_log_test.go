@@ -0,0 +1,142 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/fun-think/gologger"
+	"github.com/fun-think/gologger/format"
+	"github.com/fun-think/gologger/middleware"
+)
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := &gologger.Logger{Level: gologger.DEBUG, Format: new(format.JSONFormat), Output: &buf}
+
+	handler := (&middleware.AccessLog{Logger: logger}).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gologger.FromContext(r.Context()).Info("in handler")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("missing"))
+	}))
+
+	req := httptest.NewRequest("GET", "/a?b=c", nil)
+	req.Header.Set("X-Request-Id", "abc")
+	req.Header.Set("User-Agent", "test")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Header().Get("X-Request-Id") != "abc" {
+		t.Errorf("request id not echoed: %v", rec.Header())
+	}
+
+	dec := json.NewDecoder(&buf)
+	var inHandler, access map[string]any
+	if err := dec.Decode(&inHandler); err != nil {
+		t.Fatal(err)
+	}
+	if err := dec.Decode(&access); err != nil {
+		t.Fatal(err)
+	}
+
+	if inHandler["request_id"] != "abc" {
+		t.Errorf("handler logger has no request id: %v", inHandler)
+	}
+	want := map[string]any{
+		"level":      "WARN",
+		"msg":        "GET /a 404",
+		"method":     "GET",
+		"path":       "/a",
+		"status":     404.0,
+		"bytes":      7.0,
+		"user_agent": "test",
+		"request_id": "abc",
+	}
+	for k, v := range want {
+		if access[k] != v {
+			t.Errorf("%s = %v, want %v", k, access[k], v)
+		}
+	}
+}
+
+func TestAccessLogCommon(t *testing.T) {
+	var buf bytes.Buffer
+	logger := &gologger.Logger{Level: gologger.INFO, Format: &format.TextFormat{AppName: "app"}, Output: &buf}
+
+	handler := (&middleware.AccessLog{Logger: logger, Format: middleware.Combined}).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+
+	req := httptest.NewRequest("POST", "/x", nil)
+	req.SetBasicAuth("bob", "secret")
+	req.Header.Set("Referer", "http://ref/")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	re := regexp.MustCompile(` INFO .* 192\.0\.2\.1 - bob \[[^\]]+\] "POST /x HTTP/1\.1" 200 2 "http://ref/" "" request_id=\w+\n$`)
+	if !re.Match(buf.Bytes()) {
+		t.Errorf("unexpected line: %q", buf.String())
+	}
+}
+
+func TestAccessLogHostileUser(t *testing.T) {
+	var buf bytes.Buffer
+	logger := &gologger.Logger{Level: gologger.INFO, Format: &format.TextFormat{AppName: "app"}, Output: &buf}
+	handler := (&middleware.AccessLog{Logger: logger, Format: middleware.Common}).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusEarlyHints)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.SetBasicAuth("bob\n\"GET / HTTP/1.1\" 200 0 x\\y", "secret")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	want := ` - bob\x0a\x22GET\x20/\x20HTTP/1.1\x22\x20200\x200\x20x\x5cy [`
+	if got := buf.String(); !strings.Contains(got, want) || !strings.Contains(got, `"GET / HTTP/1.1" 204 0`) {
+		t.Errorf("unexpected line: %q", got)
+	}
+}
+
+func TestAccessLogPanic(t *testing.T) {
+	var buf bytes.Buffer
+	logger := &gologger.Logger{Level: gologger.DEBUG, Format: new(format.JSONFormat), Output: &buf}
+	handler := (&middleware.AccessLog{Logger: logger}).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("recovered %v", p)
+			}
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}()
+
+	var access map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &access); err != nil || access["status"] != 500.0 || access["level"] != "ERROR" {
+		t.Errorf("logged %s", buf.String())
+	}
+}
+
+func TestAccessLogRequestID(t *testing.T) {
+	handler := (&middleware.AccessLog{Logger: &gologger.Logger{}}).Wrap(http.NotFoundHandler())
+	for id, kept := range map[string]bool{
+		"abc-123_x.y:z":             true,
+		"":                          false,
+		"bad id\nINFO forged":       false,
+		strings.Repeat("a", 129):    false,
+		"<script>alert(1)</script>": false,
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Request-Id", id)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if got := rec.Header().Get("X-Request-Id"); (got == id) != kept || got == "" {
+			t.Errorf("%q: echoed %q", id, got)
+		}
+	}
+}
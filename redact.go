@@ -0,0 +1,206 @@
+package gologger
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// DefaultRedactionMarker replaces redacted values
+const DefaultRedactionMarker = "[REDACTED]"
+
+// Redactor is implemented by values which must not be logged as is, Redact
+// returns the value logged instead. It applies to arguments of logging
+// methods and to field values, not to values nested in them
+type Redactor interface {
+	Redact() any
+}
+
+// Redaction masks sensitive data of entries before they are formatted
+type Redaction struct {
+	// Keys are field keys whose values are masked, case insensitive, also
+	// as the last part of a key like access_token for token.
+	// key=value, key: value and "key":"value" in messages are masked too
+	Keys []string
+
+	// Patterns are masked in messages and string field values, matches of
+	// CreditCardPattern only if they pass the Luhn check
+	Patterns []*regexp.Regexp
+
+	Marker string // default is DefaultRedactionMarker
+
+	init   sync.Once
+	keys   map[string]bool
+	keysRe *regexp.Regexp
+}
+
+// Common patterns of sensitive data
+var (
+	CreditCardPattern  = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+	BearerTokenPattern = regexp.MustCompile(`(?i)\bbearer\s+[a-z0-9\-._~+/]+=*`)
+	EmailPattern       = regexp.MustCompile(`\b[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}\b`)
+)
+
+// DefaultRedaction returns a Redaction of passwords, tokens, credit cards and emails
+func DefaultRedaction() *Redaction {
+	return &Redaction{
+		Keys:     []string{"password", "passwd", "secret", "token", "authorization", "api_key", "apikey"},
+		Patterns: []*regexp.Regexp{BearerTokenPattern, CreditCardPattern, EmailPattern},
+	}
+}
+
+func (r *Redaction) marker() string {
+	if r.Marker == "" {
+		return DefaultRedactionMarker
+	}
+	return r.Marker
+}
+
+func (r *Redaction) compile() {
+	r.keys = make(map[string]bool, len(r.Keys))
+	quoted := make([]string, 0, len(r.Keys))
+	for _, k := range r.Keys {
+		r.keys[strings.ToLower(k)] = true
+		quoted = append(quoted, regexp.QuoteMeta(k))
+	}
+	if len(quoted) > 0 {
+		// key not preceded by a letter or digit, so access_token matches
+		// token, optionally quoted, followed by = or : and a quoted or bare
+		// value, which may start with an authorization scheme
+		r.keysRe = regexp.MustCompile(`(?i)((?:^|[^a-z0-9])(?:` + strings.Join(quoted, "|") + `)"?\s*[:=]\s*)` +
+			`((?:(?:bearer|basic|digest)\s+)?(?:"(?:[^"\\]|\\.)*"|[^\s,;&})\]]+))`)
+	}
+}
+
+// Message returns msg with key values and patterns masked
+func (r *Redaction) Message(msg string) string {
+	r.init.Do(r.compile)
+
+	if r.keysRe != nil {
+		msg = r.keysRe.ReplaceAllString(msg, "${1}"+r.marker())
+	}
+	for _, re := range r.Patterns {
+		if re == CreditCardPattern {
+			msg = re.ReplaceAllStringFunc(msg, func(s string) string {
+				if luhn(s) {
+					return r.marker()
+				}
+				return s
+			})
+			continue
+		}
+		msg = re.ReplaceAllLiteralString(msg, r.marker())
+	}
+	return msg
+}
+
+// isKey indicates whether the field key k is one of Keys, or ends with one
+// after a char which is not a letter or digit
+func (r *Redaction) isKey(k string) bool {
+	k = strings.ToLower(k)
+	if r.keys[k] {
+		return true
+	}
+	for key := range r.keys {
+		if i := len(k) - len(key); i > 0 && k[i:] == key && !isAlphanumeric(k[i-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+func isAlphanumeric(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// luhn indicates whether the digits of s have a valid Luhn check digit, as
+// card numbers have
+func luhn(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// Fields returns a copy of fields with sensitive values masked
+func (r *Redaction) Fields(fields Fields) Fields {
+	r.init.Do(r.compile)
+
+	redacted := make(Fields, len(fields))
+	for k, v := range fields {
+		switch {
+		case r.isKey(k):
+			v = r.marker()
+		case isRedactor(v):
+			v = v.(Redactor).Redact()
+		}
+		if s, ok := v.(string); ok {
+			v = r.Message(s)
+		}
+		redacted[k] = v
+	}
+	return redacted
+}
+
+// noRedaction only replaces Redactor values
+var noRedaction = new(Redaction)
+
+// redact applies the Redaction of l to msg and fields
+func (l *Logger) redact(msg string, fields Fields) (string, Fields) {
+	r := l.Redaction
+	if r == nil {
+		if !hasRedactor(fields) {
+			return msg, fields
+		}
+		r = noRedaction
+	} else {
+		msg = r.Message(msg)
+	}
+	if len(fields) == 0 {
+		return msg, fields
+	}
+	return msg, r.Fields(fields)
+}
+
+func isRedactor(v any) bool {
+	_, ok := v.(Redactor)
+	return ok
+}
+
+func hasRedactor(fields Fields) bool {
+	for _, v := range fields {
+		if isRedactor(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactArgs replaces Redactor arguments by their redacted value
+func redactArgs(args []any) []any {
+	var redacted []any
+	for i, arg := range args {
+		if r, ok := arg.(Redactor); ok {
+			if redacted == nil {
+				redacted = append([]any(nil), args...)
+			}
+			redacted[i] = r.Redact()
+		}
+	}
+	if redacted == nil {
+		return args
+	}
+	return redacted
+}
@@ -0,0 +1,41 @@
+package gologger
+
+import "sync/atomic"
+
+// Stats is a snapshot of logger counters
+type Stats struct {
+	Lines        map[Level]uint64 // entries logged per level
+	BytesWritten uint64
+	WriteErrors  uint64
+	Dropped      uint64 // entries not written to any output
+}
+
+// counters are updated lock-free, child loggers share the counters of their parent
+type counters struct {
+	lines        [DEBUG + 1]atomic.Uint64
+	bytesWritten atomic.Uint64
+	writeErrors  atomic.Uint64
+	dropped      atomic.Uint64
+}
+
+// countLine counts an entry at level, levels above DEBUG are not counted
+func (c *counters) countLine(level Level) {
+	if level < Level(len(c.lines)) {
+		c.lines[level].Add(1)
+	}
+}
+
+// Stats returns a snapshot of counters
+func (l *Logger) Stats() Stats {
+	c := &l.lockOwner().counters
+	stats := Stats{
+		Lines:        make(map[Level]uint64, len(c.lines)),
+		BytesWritten: c.bytesWritten.Load(),
+		WriteErrors:  c.writeErrors.Load(),
+		Dropped:      c.dropped.Load(),
+	}
+	for level := FATAL; level <= DEBUG; level++ {
+		stats.Lines[level] = c.lines[level].Load()
+	}
+	return stats
+}
@@ -0,0 +1,38 @@
+package gologger
+
+import (
+	"log"
+	"strings"
+)
+
+// StdLogger returns a standard library logger whose output is written through l at level,
+// the Level and Output of l at the time of the write apply. Its flags must stay 0,
+// otherwise the date, time and file are part of the message
+func (l *Logger) StdLogger(level Level) *log.Logger {
+	return log.New(&stdLogWriter{logger: l, level: level}, "", 0)
+}
+
+// RedirectStdLog sends output of the standard log package through logger at INFO level,
+// the prefix of the standard logger is stripped from messages. It sets the flags to 0,
+// which must not be changed afterwards
+func RedirectStdLog(logger *Logger) {
+	log.SetFlags(0)
+	log.SetOutput(&stdLogWriter{logger: logger, level: INFO, prefix: log.Prefix()})
+}
+
+// stdLogWriter emits lines of a log.Logger with flags 0 as entries,
+// prefix must match the prefix of the log.Logger
+type stdLogWriter struct {
+	logger *Logger
+	level  Level
+	prefix string
+}
+
+// Write implements io.Writer
+func (w *stdLogWriter) Write(p []byte) (n int, err error) {
+	if w.level != OFF && w.logger.Level >= w.level {
+		msg := strings.TrimPrefix(string(p), w.prefix)
+		w.logger.log(w.level, strings.TrimSuffix(msg, "\n"))
+	}
+	return len(p), nil
+}
@@ -0,0 +1,30 @@
+package gologger
+
+import "io"
+
+// Sink is an output of a Logger with its own minimum level and format
+type Sink struct {
+	Level  Level
+	Format Format
+	Output io.Writer
+}
+
+// Enabled indicates whether entries of level are written to sink
+func (s *Sink) Enabled(level Level) bool {
+	return level != OFF && s.Level >= level
+}
+
+// NewTee creates a Logger writing each entry to every sink whose level allows it.
+// Logger.Level is set to the most verbose level of sinks
+func NewTee(sinks ...*Sink) *Logger {
+	level := OFF
+	for _, sink := range sinks {
+		if sink.Level > level {
+			level = sink.Level
+		}
+	}
+	return &Logger{
+		Level: level,
+		Sinks: sinks,
+	}
+}
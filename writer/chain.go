@@ -0,0 +1,457 @@
+package writer
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// chainCheckpoint starts the checkpoint written at the start of every file
+const chainCheckpoint = "# chain checkpoint"
+
+// ChainWriter makes log files tamper-evident. It appends to every line a
+// chain value, the hash of the previous chain value and the line, as
+// ` chain=<hex>`, or as a "chain" key for JSONFormat lines without one.
+// Lines of a multi-line record which look like chained lines or checkpoints
+// are escaped with a backslash, so records can not be forged.
+//
+// When Writer is a DailyFileWriter, NewFileWriter or SizeFileWriter, every
+// file starts with a checkpoint line naming the previous file and its last
+// chain value, and a restarted process continues the chain of the current
+// file, after verifying its last record and truncating what a crash left
+// after it. Files without a valid chain are not appended to.
+// Files are checked with ChainVerifier or `gologger verify`.
+type ChainWriter struct {
+	Writer io.Writer
+
+	// Key makes chain values HMAC-SHA256, so they can not be recomputed
+	// after editing a file without it, default is plain SHA-256
+	Key []byte
+
+	prev    [sha256.Size]byte
+	file    string // current file, "" before the first one
+	openErr error  // of continuing the current file, returned until the next one
+}
+
+// Write implements io.Writer
+func (w *ChainWriter) Write(p []byte) (n int, err error) {
+	body := escapeRecord(bytes.TrimSuffix(p, []byte{'\n'}))
+
+	r, ok := w.Writer.(rotator)
+	if !ok {
+		if err := w.writeRecord(w.Writer.Write, body); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	name, size, err := r.rotate()
+	if err != nil {
+		return 0, err
+	}
+	if name != "" {
+		w.openErr = w.opened(r, name, size)
+	}
+	if w.openErr != nil {
+		return 0, w.openErr
+	}
+	if err := w.writeRecord(r.writeFile, body); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Sync commits Writer to stable storage if it supports it
+func (w *ChainWriter) Sync() error {
+	if s, ok := w.Writer.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// Reopen reopens Writer if it supports it
+func (w *ChainWriter) Reopen() error {
+	if r, ok := w.Writer.(Reopener); ok {
+		return r.Reopen()
+	}
+	return nil
+}
+
+// opened continues the chain of a file written before, or writes a
+// checkpoint linking the file to the previous one
+func (w *ChainWriter) opened(r rotator, name string, size int64) error {
+	prevFile := "-" // chain start
+	if w.file != "" {
+		prevFile = filepath.Base(w.file)
+	} else if size > 0 {
+		sum, end, err := lastChainValue(name, size, w.Key)
+		if err != nil {
+			return err
+		}
+		// a crash may have left a partial record after it
+		if end < size {
+			if err := os.Truncate(name, end); err != nil {
+				return err
+			}
+		}
+		if end > 0 {
+			w.prev = sum
+			w.file = name
+			return nil
+		}
+	}
+	if w.file == "" {
+		w.prev = [sha256.Size]byte{}
+	}
+	w.file = name
+
+	checkpoint := fmt.Sprintf("%s file=%s last=%x time=%s",
+		chainCheckpoint, prevFile, w.prev, time.Now().Format(time.RFC3339Nano))
+	return w.writeRecord(r.writeFile, []byte(checkpoint))
+}
+
+// writeRecord writes body with its chain value
+func (w *ChainWriter) writeRecord(write func([]byte) (int, error), body []byte) error {
+	sum := chainSum(w.Key, w.prev, body)
+
+	_, err := write(appendChainValue(make([]byte, 0, len(body)+80), body, sum))
+	if err != nil {
+		return err
+	}
+	w.prev = sum
+	return nil
+}
+
+// escapeRecord returns body with a backslash added to the end of lines
+// which are not the last one and look like chained lines, and before a
+// leading checkpoint
+func escapeRecord(body []byte) []byte {
+	var escaped []byte
+	if bytes.HasPrefix(body, []byte(chainCheckpoint)) {
+		escaped = append([]byte{'\\'}, body...)
+	}
+	for start := 0; ; {
+		i := bytes.IndexByte(body[start:], '\n')
+		if i < 0 {
+			break
+		}
+		end := start + i
+		if _, _, ok := splitChainValue(body[start:end]); ok {
+			if escaped == nil {
+				escaped = append([]byte(nil), body...)
+			}
+			// the offset in escaped is moved by the backslashes before
+			at := end + len(escaped) - len(body)
+			escaped = append(escaped[:at+1], escaped[at:]...)
+			escaped[at] = '\\'
+		}
+		start = end + 1
+	}
+	if escaped == nil {
+		return body
+	}
+	return escaped
+}
+
+// newChainHash returns the hash of chain values
+func newChainHash(key []byte) hash.Hash {
+	if len(key) > 0 {
+		return hmac.New(sha256.New, key)
+	}
+	return sha256.New()
+}
+
+// chainSum returns the chain value of body following prev
+func chainSum(key []byte, prev [sha256.Size]byte, body []byte) (sum [sha256.Size]byte) {
+	h := newChainHash(key)
+	h.Write(prev[:])
+	h.Write(body)
+	h.Sum(sum[:0])
+	return sum
+}
+
+// appendChainValue appends body with sum added to its last line, and a
+// newline. A JSON object gets a "chain" key, unless it may have one
+func appendChainValue(dst, body []byte, sum [sha256.Size]byte) []byte {
+	last := body[bytes.LastIndexByte(body, '\n')+1:]
+	if len(last) >= 2 && last[0] == '{' && last[len(last)-1] == '}' && !bytes.Contains(last, []byte(`"chain"`)) {
+		dst = append(dst, body[:len(body)-1]...)
+		if len(last) > 2 {
+			dst = append(dst, ',')
+		}
+		dst = append(dst, `"chain":"`...)
+		dst = appendHex(dst, sum[:])
+		return append(dst, "\"}\n"...)
+	}
+	dst = append(dst, body...)
+	dst = append(dst, " chain="...)
+	dst = appendHex(dst, sum[:])
+	return append(dst, '\n')
+}
+
+func appendHex(dst, src []byte) []byte {
+	n := len(dst)
+	dst = append(dst, make([]byte, hex.EncodedLen(len(src)))...)
+	hex.Encode(dst[n:], src)
+	return dst
+}
+
+// splitChainValue returns line without its chain value, ok is false if
+// line has none
+func splitChainValue(line []byte) (body []byte, sum [sha256.Size]byte, ok bool) {
+	const hexLen = 2 * sha256.Size
+	var value []byte
+	switch {
+	case bytes.HasSuffix(line, []byte(`"}`)) && len(line) >= hexLen+len(`{"chain":""}`):
+		value = line[len(line)-2-hexLen : len(line)-2]
+		head := line[:len(line)-2-hexLen]
+		switch {
+		case bytes.HasSuffix(head, []byte(`,"chain":"`)):
+			body = append(head[:len(head)-len(`,"chain":"`):len(head)-len(`,"chain":"`)], '}')
+		case bytes.Equal(head, []byte(`{"chain":"`)):
+			body = []byte("{}")
+		default:
+			return nil, sum, false
+		}
+	case len(line) >= hexLen+len(" chain=") && bytes.HasSuffix(line[:len(line)-hexLen], []byte(" chain=")):
+		value = line[len(line)-hexLen:]
+		body = line[:len(line)-hexLen-len(" chain=")]
+	default:
+		return nil, sum, false
+	}
+
+	if _, err := hex.Decode(sum[:], value); err != nil {
+		return nil, sum, false
+	}
+	return body, sum, true
+}
+
+// lastChainValue returns the chain value of the last record of file name
+// and the offset after it, once the record was verified. The offset is 0 if
+// the file only has a partial checkpoint left by a crash, an error is
+// returned if it has no valid chain
+func lastChainValue(name string, size int64, key []byte) (sum [sha256.Size]byte, end int64, err error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return sum, 0, err
+	}
+	defer file.Close()
+
+	// complete lines only, from the last
+	complete, tail, err := lineBefore(file, size)
+	if err != nil {
+		return sum, 0, err
+	}
+	var last chainLine
+	for pos := complete; pos > 0 && !last.ok; {
+		if last, err = readChainLine(file, pos-1); err != nil {
+			return sum, 0, err
+		}
+		pos = last.start
+	}
+	if !last.ok {
+		if complete == 0 && (bytes.HasPrefix(tail, []byte(chainCheckpoint)) || bytes.HasPrefix([]byte(chainCheckpoint), tail)) {
+			// a crash while writing the first checkpoint
+			return sum, 0, nil
+		}
+		return sum, 0, fmt.Errorf("writer: %s has no chain values, not appending to it", name)
+	}
+
+	// the record of the last chain value starts after the previous one
+	var prev chainLine
+	for pos := last.start; pos > 0 && !prev.ok; {
+		if prev, err = readChainLine(file, pos-1); err != nil {
+			return sum, 0, err
+		}
+		pos = prev.start
+	}
+	if !prev.ok {
+		// the first record of the file, usually a checkpoint
+		if _, from, ok := parseCheckpoint(last.body); ok && last.start == 0 {
+			prev.sum = from
+		}
+	}
+	ok, err := last.verify(file, key, prev.sum, prev.end)
+	if err != nil {
+		return sum, 0, err
+	}
+	if !ok {
+		return sum, 0, fmt.Errorf("writer: last record of %s does not match its chain value, not appending to it", name)
+	}
+	return last.sum, last.end, nil
+}
+
+// chainLine is a line of a file, with a chain value if ok
+type chainLine struct {
+	start, end int64 // offsets of the line and after its newline
+	body       []byte
+	sum        [sha256.Size]byte
+	ok         bool
+}
+
+// readChainLine reads the line ending with the newline at offset nl
+func readChainLine(file io.ReaderAt, nl int64) (line chainLine, err error) {
+	start, data, err := lineBefore(file, nl)
+	if err != nil {
+		return line, err
+	}
+	line.start, line.end = start, nl+1
+	line.body, line.sum, line.ok = splitChainValue(data)
+	return line, nil
+}
+
+// verify indicates whether the record of line, starting at offset from,
+// matches its chain value following prev
+func (line *chainLine) verify(file io.ReaderAt, key []byte, prev [sha256.Size]byte, from int64) (bool, error) {
+	h := newChainHash(key)
+	h.Write(prev[:])
+	if _, err := io.Copy(h, io.NewSectionReader(file, from, line.start-from)); err != nil {
+		return false, err
+	}
+	h.Write(line.body)
+	return bytes.Equal(h.Sum(nil), line.sum[:]), nil
+}
+
+// lineBefore returns the offset after the last newline before end, and the
+// bytes from it to end
+func lineBefore(file io.ReaderAt, end int64) (start int64, line []byte, err error) {
+	const chunk = 64 * 1024
+	for pos := end; pos > 0; {
+		n := int64(chunk)
+		if n > pos {
+			n = pos
+		}
+		block := make([]byte, n)
+		if _, err := file.ReadAt(block, pos-n); err != nil {
+			return 0, nil, err
+		}
+		if i := bytes.LastIndexByte(block, '\n'); i >= 0 {
+			return pos - n + int64(i) + 1, append(block[i+1:], line...), nil
+		}
+		line = append(block, line...)
+		pos -= n
+	}
+	return 0, line, nil
+}
+
+// ChainError reports the first line of a file which fails verification
+type ChainError struct {
+	File   string
+	Line   int
+	Reason string
+}
+
+func (e *ChainError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Reason)
+}
+
+// ChainVerifier checks files written by ChainWriter. Verify must be called
+// for every file in the order they were written, the chain of the first
+// one starts at its checkpoint.
+type ChainVerifier struct {
+	Key []byte
+
+	prev    [sha256.Size]byte
+	started bool
+	file    string // last verified file
+	lines   int    // lines of the last verified file
+}
+
+// Verify checks the lines read from r, and returns a *ChainError for the
+// first record which was modified, inserted or removed
+func (v *ChainVerifier) Verify(name string, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var record []byte
+	lineNo, start := 0, 0
+	first := true
+	for scanner.Scan() {
+		lineNo++
+		if len(record) == 0 {
+			start = lineNo
+		}
+
+		line := scanner.Bytes()
+		body, sum, ok := splitChainValue(line)
+		if !ok {
+			// a line of a multi-line record, e.g. a stack trace
+			record = append(record, line...)
+			record = append(record, '\n')
+			continue
+		}
+		record = append(record, body...)
+
+		if reason := v.check(record, sum, first); reason != "" {
+			return &ChainError{File: name, Line: start, Reason: reason}
+		}
+		record = record[:0]
+		first = false
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(record) > 0 {
+		return &ChainError{File: name, Line: start, Reason: "line without chain value"}
+	}
+
+	v.file, v.lines = name, lineNo
+	return nil
+}
+
+// check verifies record, and returns the reason if it fails
+func (v *ChainVerifier) check(record []byte, sum [sha256.Size]byte, first bool) string {
+	if prevFile, last, ok := parseCheckpoint(record); ok {
+		switch {
+		case prevFile == "-":
+			if !first {
+				return "chain restarted inside the file"
+			}
+			v.prev = [sha256.Size]byte{}
+		case !v.started:
+			// the files before were not given
+			v.prev = last
+		case v.file != "" && first && prevFile != strings.TrimSuffix(filepath.Base(v.file), ".gz"):
+			return fmt.Sprintf("previous file is %s, not %s", prevFile, v.file)
+		case last != v.prev:
+			return fmt.Sprintf("last chain value of %s does not match, lines after %d were removed or modified", v.file, v.lines)
+		}
+	}
+
+	if chainSum(v.Key, v.prev, record) != sum {
+		return "chain value does not match, line was modified, inserted or removed"
+	}
+	v.prev = sum
+	v.started = true
+	return ""
+}
+
+// parseCheckpoint returns the fields of a checkpoint record
+func parseCheckpoint(record []byte) (prevFile string, last [sha256.Size]byte, ok bool) {
+	if !bytes.HasPrefix(record, []byte(chainCheckpoint+" ")) {
+		return "", last, false
+	}
+	var lastHex string
+	for _, field := range strings.Fields(string(record[len(chainCheckpoint):])) {
+		key, value, _ := strings.Cut(field, "=")
+		switch key {
+		case "file":
+			prevFile = value
+		case "last":
+			lastHex = value
+		}
+	}
+	if n, err := hex.Decode(last[:], []byte(lastHex)); err != nil || n != len(last) || prevFile == "" {
+		return "", last, false
+	}
+	return prevFile, last, true
+}
@@ -0,0 +1,230 @@
+package writer_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fun-think/gologger"
+	"github.com/fun-think/gologger/format"
+	"github.com/fun-think/gologger/writer"
+)
+
+// verifyChain verifies files in order
+func verifyChain(key []byte, files ...string) error {
+	v := &writer.ChainVerifier{Key: key}
+	for _, name := range files {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if err := v.Verify(name, bytes.NewReader(data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestChainWriter(t *testing.T) {
+	key := []byte("secret")
+	name := filepath.Join(t.TempDir(), "audit")
+
+	logger := &gologger.Logger{
+		Level:           gologger.INFO,
+		Format:          &format.TextFormat{},
+		Output:          &writer.ChainWriter{Writer: &writer.SizeFileWriter{Name: name, MaxSize: 500, MaxCount: 10}, Key: key},
+		StacktraceLevel: gologger.ERROR,
+	}
+	for i := 0; i < 4; i++ {
+		logger.Infof("line %d", i)
+	}
+	logger.Error("with stack")
+
+	// a restarted process continues the chain, and JSON lines keep valid
+	logger.Output = &writer.ChainWriter{Writer: &writer.SizeFileWriter{Name: name, MaxSize: 500, MaxCount: 10}, Key: key}
+	logger.Format = &format.JSONFormat{}
+	for i := 0; i < 4; i++ {
+		logger.Infof("json %d", i)
+	}
+
+	var files []string
+	for i := 0; ; i++ {
+		file := fmt.Sprintf("%s.%d.log", name, i)
+		if _, err := os.Stat(file); err != nil {
+			break
+		}
+		files = append(files, file)
+	}
+	if len(files) < 3 {
+		t.Fatalf("%d files", len(files))
+	}
+
+	if err := verifyChain(key, files...); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyChain([]byte("other"), files...); err == nil {
+		t.Error("verified with the wrong key")
+	}
+	if err := verifyChain(key, files[1:]...); err != nil {
+		t.Errorf("without first file: %v", err)
+	}
+
+	data, _ := os.ReadFile(files[1])
+	lines := strings.Split(string(data), "\n")
+	if !strings.HasPrefix(lines[0], "# chain checkpoint file=audit.0.log ") {
+		t.Errorf("no checkpoint: %s", lines[0])
+	}
+
+	// edit the second line
+	lines[1] = strings.Replace(lines[1], "INFO", "WARN", 1)
+	os.WriteFile(files[1], []byte(strings.Join(lines, "\n")), 0644)
+	err := verifyChain(key, files...)
+	var chainErr *writer.ChainError
+	if !errors.As(err, &chainErr) || chainErr.File != files[1] || chainErr.Line != 2 {
+		t.Errorf("edited line: %v", err)
+	}
+
+	// remove the last line of the first file
+	os.WriteFile(files[1], data, 0644)
+	data, _ = os.ReadFile(files[0])
+	data = data[:bytes.LastIndexByte(data[:len(data)-1], '\n')+1]
+	os.WriteFile(files[0], data, 0644)
+	err = verifyChain(key, files...)
+	if !errors.As(err, &chainErr) || chainErr.File != files[1] || chainErr.Line != 1 {
+		t.Errorf("truncated file: %v", err)
+	}
+}
+
+func TestChainWriterPlain(t *testing.T) {
+	var buf bytes.Buffer
+	w := &writer.ChainWriter{Writer: &buf}
+	for i := 0; i < 3; i++ {
+		fmt.Fprintf(w, "line %d\n", i)
+	}
+	fmt.Fprintf(w, "{}\n")
+
+	if err := verifyChainReader(buf.String()); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyChainReader(strings.Replace(buf.String(), "line 1", "line 2", 1)); err == nil ||
+		err.Error() != "stdin:2: chain value does not match, line was modified, inserted or removed" {
+		t.Errorf("got %v", err)
+	}
+}
+
+func verifyChainReader(s string) error {
+	return new(writer.ChainVerifier).Verify("stdin", strings.NewReader(s))
+}
+
+func TestChainWriterCrash(t *testing.T) {
+	key := []byte("secret")
+	name := filepath.Join(t.TempDir(), "audit")
+	newWriter := func() *writer.ChainWriter {
+		return &writer.ChainWriter{Writer: &writer.SizeFileWriter{Name: name, MaxSize: 1 << 20, MaxCount: 2}, Key: key}
+	}
+
+	w := newWriter()
+	fmt.Fprintln(w, "line 0")
+	fmt.Fprintln(w, "line 1")
+
+	// a crash while writing a record with a stack trace
+	file, _ := os.OpenFile(name+".0.log", os.O_WRONLY|os.O_APPEND, 0)
+	file.WriteString("line 2\n\tmain.main()\n\tmain.go:12 cha")
+	file.Close()
+
+	w = newWriter()
+	fmt.Fprintln(w, "line 3")
+
+	if err := verifyChain(key, name+".0.log"); err != nil {
+		t.Error(err)
+	}
+	data, _ := os.ReadFile(name + ".0.log")
+	if bytes.Contains(data, []byte("line 2")) || !bytes.Contains(data, []byte("line 3")) {
+		t.Errorf("file is %q", data)
+	}
+
+	// a partial record larger than the read buffer
+	file, _ = os.OpenFile(name+".0.log", os.O_WRONLY|os.O_APPEND, 0)
+	file.WriteString(strings.Repeat("stack frame\n", 20000) + "main.go")
+	file.Close()
+	w = newWriter()
+	fmt.Fprintln(w, "line 4")
+	if err := verifyChain(key, name+".0.log"); err != nil {
+		t.Error(err)
+	}
+
+	// a crash while writing the first checkpoint
+	os.Remove(name)
+	os.WriteFile(name+".1.log", []byte("# chain check"), 0644)
+	os.Symlink("audit.1.log", name)
+	w = newWriter()
+	fmt.Fprintln(w, "line 5")
+	if err := verifyChain(key, name+".1.log"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestChainWriterInvalidFile(t *testing.T) {
+	key := []byte("secret")
+	name := filepath.Join(t.TempDir(), "audit")
+	newWriter := func(key []byte) *writer.ChainWriter {
+		return &writer.ChainWriter{Writer: &writer.SizeFileWriter{Name: name, MaxSize: 1 << 20, MaxCount: 2}, Key: key}
+	}
+
+	w := newWriter(key)
+	fmt.Fprintln(w, "line 0")
+	fmt.Fprintln(w, "line 1")
+	data, _ := os.ReadFile(name + ".0.log")
+
+	for _, c := range []struct {
+		name    string
+		content []byte
+		key     []byte
+	}{
+		{"other key", data, []byte("other")},
+		{"modified last line", bytes.Replace(data, []byte("line 1"), []byte("line 2"), 1), key},
+		{"unchained", []byte("plain\n"), key},
+	} {
+		os.WriteFile(name+".0.log", c.content, 0644)
+		w := newWriter(c.key)
+		for i := 0; i < 2; i++ {
+			if _, err := fmt.Fprintln(w, "appended"); err == nil {
+				t.Errorf("%s: appended", c.name)
+			}
+		}
+		if got, _ := os.ReadFile(name + ".0.log"); !bytes.Equal(got, c.content) {
+			t.Errorf("%s: file changed to %q", c.name, got)
+		}
+	}
+}
+
+func TestChainWriterForgery(t *testing.T) {
+	key := []byte("secret")
+	name := filepath.Join(t.TempDir(), "audit")
+	newWriter := func() *writer.ChainWriter {
+		return &writer.ChainWriter{Writer: &writer.SizeFileWriter{Name: name, MaxSize: 1 << 20, MaxCount: 2}, Key: key}
+	}
+
+	suffix := " chain=" + strings.Repeat("ab", 32)
+	w := newWriter()
+	fmt.Fprintln(w, "ends with"+suffix)
+	fmt.Fprintln(w, "inner line"+suffix+"\n{\"chain\":\""+strings.Repeat("ab", 32)+"\"}\nlast")
+	fmt.Fprintln(w, "# chain checkpoint file=- last="+strings.Repeat("00", 32)+" time=now")
+	fmt.Fprintln(w, `{"msg":"x","chain":"mine"}`)
+
+	// a restarted process finds the last record
+	w = newWriter()
+	fmt.Fprintln(w, "multi line"+suffix)
+
+	if err := verifyChain(key, name+".0.log"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(name + ".0.log")
+	if bytes.Count(data, []byte(`"chain":`)) != 2 {
+		t.Errorf("duplicate chain key:\n%s", data)
+	}
+}
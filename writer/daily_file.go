@@ -3,60 +3,134 @@ package writer
 import (
 	"fmt"
 	"os"
-	"path"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
+
+	"github.com/fun-think/gologger"
 )
 
+// CurrentLink is the symlink to the current file in the directory of DailyFileWriter
+const CurrentLink = "current"
+
 // DailyFileWriter create new log for every day
 type DailyFileWriter struct {
-	Name     string
+	Name     string // directory of the files, created if missing
 	MaxCount int
+	FileOptions
+
+	// CheckInterval is how often the current file is checked for being
+	// moved or deleted to reopen it, default is 1 second, negative disables
+	// checking
+	CheckInterval time.Duration
+
+	// ErrorHandler is called when CurrentLink can not be updated, which
+	// does not fail writes, default reports to stderr
+	ErrorHandler gologger.ErrorHandler
 
 	file        *os.File
+	syncErr     error // of closed files, returned by Sync
+	check       moveCheck
 	nextDayTime int64
+	reopen      atomic.Bool
+	counters    counters
+}
+
+// Stats returns a snapshot of counters
+func (w *DailyFileWriter) Stats() Stats {
+	return w.counters.snapshot()
 }
 
 // Write implements io.Writer
 func (w *DailyFileWriter) Write(p []byte) (n int, err error) {
+	if _, _, err := w.rotate(); err != nil {
+		return 0, err
+	}
+	return w.writeFile(p)
+}
+
+// Sync commits the current file to stable storage, and returns the error
+// of committing the files closed since the last Sync
+func (w *DailyFileWriter) Sync() error {
+	return syncFile(w.file, &w.syncErr)
+}
+
+// Reopen makes the next write reopen the file, after it was moved by
+// external rotation like logrotate
+func (w *DailyFileWriter) Reopen() error {
+	w.reopen.Store(true)
+	return nil
+}
+
+func (w *DailyFileWriter) rotate() (name string, size int64, err error) {
 	now := time.Now()
+	reopen := w.reopen.Swap(false)
 
 	if w.file == nil {
 		err := w.openFile(&now)
 		if err != nil {
-			return 0, err
+			return "", 0, err
 		}
 	} else if now.Unix() >= w.nextDayTime {
-		w.file.Close()
+		closeFile(w.file, &w.syncErr)
+		w.file = nil
+		err := w.openFile(&now)
+		if err != nil {
+			return "", 0, err
+		}
+		w.counters.rotations.Add(1)
+	} else if reopen || w.check.moved(w.file, w.CheckInterval) {
+		closeFile(w.file, &w.syncErr)
+		w.file = nil
 		err := w.openFile(&now)
 		if err != nil {
-			return 0, err
+			return "", 0, err
 		}
+	} else {
+		return "", 0, nil
 	}
 
-	return w.file.Write(p)
+	return w.file.Name(), w.counters.currentSize.Load(), nil
+}
+
+func (w *DailyFileWriter) writeFile(p []byte) (n int, err error) {
+	n, err = w.file.Write(p)
+	w.counters.currentSize.Add(int64(n))
+	return n, err
 }
 
 func (w *DailyFileWriter) openFile(now *time.Time) (err error) {
 	name := fmt.Sprintf("%s/%s.log", w.Name, now.Format("20060102"))
-	stat, err := os.Stat(w.Name)
-	if err != nil {
-		return err
-	}
-	if !stat.IsDir() {
+	if stat, err := os.Stat(w.Name); err == nil && !stat.IsDir() {
 		return fmt.Errorf("%s is not a dir", w.Name)
 	}
 
-	w.file, err = os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	file, err := w.FileOptions.openFile(name, os.O_RDWR|os.O_CREATE|os.O_APPEND)
+	if err != nil {
+		return err
+	}
+	stat, err := file.Stat()
 	if err != nil {
+		file.Close()
 		return err
 	}
+	w.file = file
+	w.counters.currentSize.Store(stat.Size())
 
 	year, month, day := now.Date()
 	w.nextDayTime = time.Date(year, month, day+1, 0, 0, 0, 0, now.Location()).Unix()
 
+	// the link is a convenience, writes go on without it
+	if err := w.linkCurrent(filepath.Base(name), filepath.Join(w.Name, CurrentLink)); err != nil {
+		if w.ErrorHandler != nil {
+			w.ErrorHandler(err)
+		} else {
+			linkErrors.Report(err)
+		}
+	}
+
 	if w.MaxCount > 0 {
 		go w.cleanFiles()
 	}
@@ -64,20 +138,21 @@ func (w *DailyFileWriter) openFile(now *time.Time) (err error) {
 	return nil
 }
 
+// linkErrors reports link errors of writers without ErrorHandler
+var linkErrors = new(gologger.ErrorReporter)
+
 // clean old files
 func (w *DailyFileWriter) cleanFiles() {
-	dir := path.Dir(w.Name)
+	dir := w.Name
 
 	fileList, err := os.ReadDir(dir)
 	if err != nil {
 		return
 	}
 
-	prefix := path.Base(w.Name) + "."
-
 	var matches []string
 	for _, f := range fileList {
-		if !f.IsDir() && strings.HasPrefix(f.Name(), prefix) {
+		if !f.IsDir() && isDailyFileName(f.Name()) {
 			matches = append(matches, f.Name())
 		}
 	}
@@ -87,7 +162,19 @@ func (w *DailyFileWriter) cleanFiles() {
 
 		for _, f := range matches[w.MaxCount:] {
 			file := filepath.Join(dir, f)
-			os.Remove(file)
+			if os.Remove(file) == nil {
+				w.counters.filesDeleted.Add(1)
+			}
 		}
 	}
 }
+
+// isDailyFileName indicates whether name is YYYYMMDD.log
+func isDailyFileName(name string) bool {
+	day, ok := strings.CutSuffix(name, ".log")
+	if !ok || len(day) != 8 {
+		return false
+	}
+	_, err := time.Parse("20060102", day)
+	return err == nil
+}
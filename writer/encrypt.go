@@ -0,0 +1,322 @@
+package writer
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Encrypted files are segments of a header followed by chunks. A segment is
+// started for every file and every time a file written before is appended to,
+// after truncating the chunk a crash may have left incomplete.
+//
+//	header: encryptMagic, version byte, key id length byte, key id
+//	chunk:  uint32 big endian length of ciphertext, 12 bytes nonce, ciphertext
+//
+// The additional data of a chunk is its header and its index in the segment,
+// so chunks can not be moved, and every chunk decrypts on its own.
+const (
+	encryptVersion = 1
+	maxChunkSize   = 1 << 30
+	gcmNonceSize   = 12
+)
+
+// encryptMagic is not a valid chunk length, so headers and chunks are told apart
+var encryptMagic = [4]byte{0xff, 'G', 'L', 'E'}
+
+var (
+	// ErrTruncatedChunk is returned when an encrypted file ends inside a
+	// chunk, the last chunk is lost when the writing process crashes
+	ErrTruncatedChunk = errors.New("encrypted file ends in a truncated chunk")
+
+	// ErrNotEncrypted is returned when a file does not start with a header
+	ErrNotEncrypted = errors.New("not an encrypted log file")
+)
+
+// EncryptWriter encrypts with AES-GCM, every Write as a chunk which is
+// decrypted on its own, so a crash loses at most the entry being written.
+// Use DecryptReader or `gologger decrypt` to read files back.
+//
+// When Writer is a DailyFileWriter, NewFileWriter or SizeFileWriter, every
+// file gets a header with the id of its key, so keys are rotated per file.
+type EncryptWriter struct {
+	Writer io.Writer
+
+	// Key returns the id and the 16, 24 or 32 bytes AES key for a new file,
+	// it is called at every rotation so the key can change between files
+	Key func() (id string, key []byte, err error)
+
+	aead   cipher.AEAD
+	header []byte
+	index  uint64 // of the next chunk in the segment
+}
+
+// Write implements io.Writer
+func (w *EncryptWriter) Write(p []byte) (n int, err error) {
+	if _, _, err := w.rotate(); err != nil {
+		return 0, err
+	}
+	return w.writeFile(p)
+}
+
+// Sync commits Writer to stable storage if it supports it
+func (w *EncryptWriter) Sync() error {
+	if s, ok := w.Writer.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// Reopen reopens Writer if it supports it
+func (w *EncryptWriter) Reopen() error {
+	if r, ok := w.Writer.(Reopener); ok {
+		return r.Reopen()
+	}
+	return nil
+}
+
+func (w *EncryptWriter) rotate() (name string, size int64, err error) {
+	if r, ok := w.Writer.(rotator); ok {
+		name, size, err = r.rotate()
+		if err != nil || name == "" {
+			return name, size, err
+		}
+		if size > 0 {
+			if size, err = truncateChunks(name, size); err != nil {
+				return "", 0, err
+			}
+		}
+	} else if w.aead != nil {
+		return "", 0, nil
+	}
+
+	if err := w.startSegment(); err != nil {
+		return "", 0, err
+	}
+	return name, size, nil
+}
+
+// startSegment writes a header with the current key
+func (w *EncryptWriter) startSegment() error {
+	w.aead = nil
+	if w.Key == nil {
+		return errors.New("EncryptWriter has no Key")
+	}
+	id, key, err := w.Key()
+	if err != nil {
+		return err
+	}
+	if len(id) > 255 {
+		return fmt.Errorf("key id %q is too long", id)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+
+	header := append(encryptMagic[:], encryptVersion, byte(len(id)))
+	header = append(header, id...)
+	if _, err := w.write(header); err != nil {
+		return err
+	}
+	w.aead, w.header, w.index = aead, header, 0
+	return nil
+}
+
+func (w *EncryptWriter) writeFile(p []byte) (n int, err error) {
+	if w.aead == nil {
+		// the header of the current file could not be written
+		if err := w.startSegment(); err != nil {
+			return 0, err
+		}
+	}
+
+	size := w.aead.NonceSize() + len(p) + w.aead.Overhead()
+	chunk := make([]byte, 4, 4+size)
+	binary.BigEndian.PutUint32(chunk, uint32(size-w.aead.NonceSize()))
+	chunk = chunk[:4+w.aead.NonceSize()]
+	if _, err := rand.Read(chunk[4:]); err != nil {
+		return 0, err
+	}
+	chunk = w.aead.Seal(chunk, chunk[4:], p, chunkAdditionalData(w.header, w.index))
+
+	if _, err := w.write(chunk); err != nil {
+		return 0, err
+	}
+	w.index++
+	return len(p), nil
+}
+
+// write writes to the current file of Writer
+func (w *EncryptWriter) write(p []byte) (int, error) {
+	if r, ok := w.Writer.(rotator); ok {
+		return r.writeFile(p)
+	}
+	return w.Writer.Write(p)
+}
+
+// truncateChunks truncates the encrypted file name of size to the end of its
+// last complete header or chunk, and returns the new size. Files which are not
+// encrypted are kept.
+func truncateChunks(name string, size int64) (int64, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return 0, err
+	}
+	r := bufio.NewReader(file)
+	end, err := completeChunks(r)
+	file.Close()
+	if err != nil || end >= size {
+		return size, err
+	}
+	return end, os.Truncate(name, end)
+}
+
+// completeChunks returns the length of the complete headers and chunks read
+// from r, or the length of r if it does not start with a header
+func completeChunks(r *bufio.Reader) (end int64, err error) {
+	for {
+		var prefix [4]byte
+		n, err := io.ReadFull(r, prefix[:])
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return end, nil
+		}
+		if err != nil {
+			return end, err
+		}
+
+		var skip int
+		if prefix == encryptMagic {
+			head, err := r.Peek(2)
+			if err != nil {
+				return end, nil
+			}
+			skip = 2 + int(head[1])
+		} else if end == 0 {
+			// not encrypted, read the rest to return its length
+			rest, err := io.Copy(io.Discard, r)
+			return int64(n) + rest, err
+		} else {
+			size := binary.BigEndian.Uint32(prefix[:])
+			if size > maxChunkSize {
+				return end, nil
+			}
+			skip = gcmNonceSize + int(size)
+		}
+		if discarded, _ := r.Discard(skip); discarded < skip {
+			return end, nil
+		}
+		end += int64(len(prefix) + skip)
+	}
+}
+
+func chunkAdditionalData(header []byte, index uint64) []byte {
+	return binary.BigEndian.AppendUint64(append([]byte(nil), header...), index)
+}
+
+// DecryptReader reads the content of files written by EncryptWriter
+type DecryptReader struct {
+	Reader io.Reader
+
+	// Key returns the AES key for the key id of a file header
+	Key func(id string) ([]byte, error)
+
+	r      *bufio.Reader
+	aead   cipher.AEAD
+	header []byte
+	index  uint64
+	buf    []byte // decrypted and not yet read
+}
+
+// Read implements io.Reader
+func (d *DecryptReader) Read(p []byte) (n int, err error) {
+	if d.r == nil {
+		d.r = bufio.NewReader(d.Reader)
+	}
+	for len(d.buf) == 0 {
+		if err := d.next(); err != nil {
+			return 0, err
+		}
+	}
+	n = copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+// next reads the next header or chunk
+func (d *DecryptReader) next() error {
+	var prefix [4]byte
+	if _, err := io.ReadFull(d.r, prefix[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return ErrTruncatedChunk
+		}
+		return err
+	}
+
+	if prefix == encryptMagic {
+		return d.readHeader()
+	}
+	if d.aead == nil {
+		return ErrNotEncrypted
+	}
+
+	size := binary.BigEndian.Uint32(prefix[:])
+	if size > maxChunkSize {
+		return fmt.Errorf("invalid chunk size %d", size)
+	}
+	chunk := make([]byte, d.aead.NonceSize()+int(size))
+	if _, err := io.ReadFull(d.r, chunk); err != nil {
+		return ErrTruncatedChunk
+	}
+	nonce := chunk[:d.aead.NonceSize()]
+	plain, err := d.aead.Open(chunk[len(nonce):len(nonce)], nonce, chunk[len(nonce):], chunkAdditionalData(d.header, d.index))
+	if err != nil {
+		return fmt.Errorf("chunk %d of key %s: %w", d.index, d.header[6:], err)
+	}
+	d.index++
+	d.buf = plain
+	return nil
+}
+
+func (d *DecryptReader) readHeader() error {
+	var head [2]byte
+	if _, err := io.ReadFull(d.r, head[:]); err != nil {
+		return ErrTruncatedChunk
+	}
+	if head[0] != encryptVersion {
+		return fmt.Errorf("unsupported encrypted file version %d", head[0])
+	}
+	id := make([]byte, head[1])
+	if _, err := io.ReadFull(d.r, id); err != nil {
+		return ErrTruncatedChunk
+	}
+
+	if d.Key == nil {
+		return errors.New("DecryptReader has no Key")
+	}
+	key, err := d.Key(string(id))
+	if err != nil {
+		return err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	if d.aead, err = cipher.NewGCM(block); err != nil {
+		return err
+	}
+
+	d.header = append(append(encryptMagic[:], head[:]...), id...)
+	d.index = 0
+	return nil
+}
@@ -0,0 +1,97 @@
+package writer_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fun-think/gologger/writer"
+)
+
+func TestEncryptWriter(t *testing.T) {
+	keys := map[string][]byte{}
+	newKey := func() (string, []byte, error) {
+		id := fmt.Sprintf("k%d", len(keys))
+		keys[id] = bytes.Repeat([]byte{byte(len(keys))}, 32)
+		return id, keys[id], nil
+	}
+	getKey := func(id string) ([]byte, error) {
+		if key, ok := keys[id]; ok {
+			return key, nil
+		}
+		return nil, fmt.Errorf("unknown key %s", id)
+	}
+
+	name := filepath.Join(t.TempDir(), "secret")
+	w := &writer.EncryptWriter{Writer: &writer.SizeFileWriter{Name: name, MaxSize: 100, MaxCount: 10}, Key: newKey}
+	for i := 0; i < 6; i++ {
+		fmt.Fprintf(w, "card 4111 line %d\n", i)
+	}
+
+	var plain []string
+	for i := 0; ; i++ {
+		data, err := os.ReadFile(fmt.Sprintf("%s.%d.log", name, i))
+		if err != nil {
+			break
+		}
+		if bytes.Contains(data, []byte("4111")) {
+			t.Fatalf("file %d is not encrypted", i)
+		}
+		out, err := io.ReadAll(&writer.DecryptReader{Reader: bytes.NewReader(data), Key: getKey})
+		if err != nil {
+			t.Fatalf("file %d: %v", i, err)
+		}
+		plain = append(plain, string(out))
+	}
+	if len(plain) < 2 || len(keys) != len(plain) {
+		t.Fatalf("%d files with %d keys", len(plain), len(keys))
+	}
+	if got := strings.Join(plain, ""); strings.Count(got, "\n") != 6 || !strings.HasPrefix(got, "card 4111 line 0\n") {
+		t.Errorf("decrypted %q", got)
+	}
+
+	data, _ := os.ReadFile(name + ".0.log")
+
+	// a crash while writing loses the last chunk only
+	out, err := io.ReadAll(&writer.DecryptReader{Reader: bytes.NewReader(data[:len(data)-3]), Key: getKey})
+	if !errors.Is(err, writer.ErrTruncatedChunk) || string(out) != plain[0][:strings.LastIndex(plain[0][:len(plain[0])-1], "\n")+1] {
+		t.Errorf("truncated: %q, %v", out, err)
+	}
+
+	data[len(data)-1] ^= 1
+	if _, err := io.ReadAll(&writer.DecryptReader{Reader: bytes.NewReader(data), Key: getKey}); err == nil {
+		t.Error("modified chunk decrypted")
+	}
+}
+
+func TestEncryptWriterCrash(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	newKey := func() (string, []byte, error) { return "k", key, nil }
+	getKey := func(string) ([]byte, error) { return key, nil }
+
+	name := filepath.Join(t.TempDir(), "secret")
+	w := &writer.EncryptWriter{Writer: &writer.SizeFileWriter{Name: name, MaxSize: 1 << 20, MaxCount: 2}, Key: newKey}
+	fmt.Fprintln(w, "line 0")
+	fmt.Fprintln(w, "line 1")
+
+	// a crash while writing line 1 leaves a partial chunk
+	file := name + ".0.log"
+	stat, _ := os.Stat(file)
+	os.Truncate(file, stat.Size()-3)
+
+	// a restarted process appends a new segment after the last complete chunk
+	w = &writer.EncryptWriter{Writer: &writer.SizeFileWriter{Name: name, MaxSize: 1 << 20, MaxCount: 2}, Key: newKey}
+	fmt.Fprintln(w, "line 2")
+	fmt.Fprintln(w, "line 3")
+
+	data, _ := os.ReadFile(file)
+	out, err := io.ReadAll(&writer.DecryptReader{Reader: bytes.NewReader(data), Key: getKey})
+	if err != nil || string(out) != "line 0\nline 2\nline 3\n" {
+		t.Errorf("decrypted %q, %v", out, err)
+	}
+}
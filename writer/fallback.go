@@ -0,0 +1,47 @@
+package writer
+
+import (
+	"errors"
+	"io"
+)
+
+// FallbackWriter writes to the first writer which succeeds, e.g.
+// a file writer, then os.Stderr, then io.Discard
+type FallbackWriter struct {
+	Writers []io.Writer
+
+	// OnError is called with the error of every writer which was skipped
+	OnError func(w io.Writer, err error)
+}
+
+// Write implements io.Writer
+func (w *FallbackWriter) Write(p []byte) (n int, err error) {
+	var errs []error
+	for _, out := range w.Writers {
+		n, err := out.Write(p)
+		if err == nil {
+			return n, nil
+		}
+		if w.OnError != nil {
+			w.OnError(out, err)
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return 0, errors.New("no writers")
+	}
+	return 0, errors.Join(errs...)
+}
+
+// Reopen reopens all Writers which support it
+func (w *FallbackWriter) Reopen() error {
+	var errs []error
+	for _, out := range w.Writers {
+		if r, ok := out.(Reopener); ok {
+			if err := r.Reopen(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,50 @@
+package writer_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/fun-think/gologger/writer"
+)
+
+// brokenWriter fails writes while broken
+type brokenWriter struct {
+	bytes.Buffer
+	broken bool
+}
+
+func (w *brokenWriter) Write(p []byte) (int, error) {
+	if w.broken {
+		return 0, errors.New("broken")
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestFallbackWriter(t *testing.T) {
+	primary, secondary := new(brokenWriter), new(brokenWriter)
+	var skipped []io.Writer
+	w := &writer.FallbackWriter{
+		Writers: []io.Writer{primary, secondary},
+		OnError: func(w io.Writer, err error) { skipped = append(skipped, w) },
+	}
+
+	w.Write([]byte("a\n"))
+	primary.broken = true
+	w.Write([]byte("b\n"))
+	primary.broken = false
+	w.Write([]byte("c\n"))
+
+	if primary.String() != "a\nc\n" || secondary.String() != "b\n" {
+		t.Errorf("primary got %q, secondary got %q", primary.String(), secondary.String())
+	}
+	if len(skipped) != 1 || skipped[0] != primary {
+		t.Errorf("skipped %v", skipped)
+	}
+
+	secondary.broken, primary.broken = true, true
+	if n, err := w.Write([]byte("d\n")); n != 0 || err == nil {
+		t.Errorf("all writers failed, got %d, %v", n, err)
+	}
+}
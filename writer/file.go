@@ -0,0 +1,179 @@
+package writer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// These are the modes of created files and directories unless configured
+const (
+	DefaultFileMode os.FileMode = 0644
+	DefaultDirMode  os.FileMode = 0755
+)
+
+// FileOptions configure how the file writers create files and directories.
+// Missing parent directories are always created
+type FileOptions struct {
+	FileMode os.FileMode // of created files, default is DefaultFileMode
+	DirMode  os.FileMode // of created directories, default is DefaultDirMode
+
+	// Chown changes the owner of created files, directories and links to UID and GID
+	Chown bool
+	UID   int
+	GID   int
+
+	// NoSymlink disables the symlink to the current file, Name for
+	// SizeFileWriter and NewFileWriter, CurrentLink in the directory of
+	// DailyFileWriter
+	NoSymlink bool
+}
+
+// openFile opens name with flag, creating it and its directory if needed
+func (o *FileOptions) openFile(name string, flag int) (*os.File, error) {
+	if err := o.mkdirAll(filepath.Dir(name)); err != nil {
+		return nil, err
+	}
+
+	_, err := os.Lstat(name)
+	created := os.IsNotExist(err)
+
+	mode := o.FileMode
+	if mode == 0 {
+		mode = DefaultFileMode
+	}
+	file, err := os.OpenFile(name, flag, mode)
+	if err != nil || !created {
+		return file, err
+	}
+
+	// the mode given to open is masked by umask
+	if o.FileMode != 0 {
+		err = file.Chmod(o.FileMode)
+	}
+	if err == nil && o.Chown {
+		err = file.Chown(o.UID, o.GID)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// mkdirAll creates dir and its missing parents
+func (o *FileOptions) mkdirAll(dir string) error {
+	var missing []string
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil || !os.IsNotExist(err) {
+			break
+		}
+		missing = append(missing, d)
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	mode := o.DirMode
+	if mode == 0 {
+		mode = DefaultDirMode
+	}
+	if err := os.MkdirAll(dir, mode); err != nil {
+		return err
+	}
+	for _, d := range missing {
+		if o.DirMode != 0 {
+			if err := os.Chmod(d, o.DirMode); err != nil {
+				return err
+			}
+		}
+		if o.Chown {
+			if err := os.Chown(d, o.UID, o.GID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// linkCurrent points the symlink link to target unless NoSymlink. The link
+// is replaced atomically by renaming a new one over it, so it always exists,
+// and anything but a symlink is never replaced
+func (o *FileOptions) linkCurrent(target, link string) error {
+	if o.NoSymlink {
+		return nil
+	}
+	if stat, err := os.Lstat(link); err == nil && stat.Mode()&os.ModeSymlink == 0 {
+		return fmt.Errorf("%s exists and is not a symlink", link)
+	}
+
+	// hidden, so it never matches the names of log files
+	tmp := filepath.Join(filepath.Dir(link), "."+filepath.Base(link)+"."+strconv.Itoa(os.Getpid())+".tmp")
+	os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	if o.Chown {
+		if err := os.Lchown(tmp, o.UID, o.GID); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+	}
+	if err := os.Rename(tmp, link); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// closeFile commits file to stable storage before closing it at rotation, so
+// a Sync after it covers the entries written before it. The error is added
+// to *syncErr for the next Sync
+func closeFile(file *os.File, syncErr *error) {
+	if err := file.Sync(); err != nil {
+		*syncErr = errors.Join(*syncErr, fmt.Errorf("sync %s: %w", file.Name(), err))
+	}
+	file.Close()
+}
+
+// syncFile commits file to stable storage, and returns the errors of closed
+// files in *syncErr too
+func syncFile(file *os.File, syncErr *error) error {
+	err := *syncErr
+	*syncErr = nil
+	if file != nil {
+		err = errors.Join(err, file.Sync())
+	}
+	return err
+}
+
+// moveCheck detects that an open file was moved or deleted, like by
+// logrotate without a signal
+type moveCheck struct {
+	checked time.Time
+}
+
+// moved indicates whether the name file was opened with is no longer file,
+// checked at most once per interval, default is 1 second, negative disables it
+func (c *moveCheck) moved(file *os.File, interval time.Duration) bool {
+	if interval == 0 {
+		interval = time.Second
+	}
+	if file == nil || interval < 0 || time.Since(c.checked) < interval {
+		return false
+	}
+	c.checked = time.Now()
+
+	current, err := file.Stat()
+	if err != nil {
+		return true
+	}
+	stat, err := os.Stat(file.Name())
+	return err != nil || !os.SameFile(current, stat)
+}
@@ -0,0 +1,147 @@
+//go:build unix
+
+package writer_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/fun-think/gologger/writer"
+)
+
+func TestFileOptions(t *testing.T) {
+	opts := writer.FileOptions{FileMode: 0600, DirMode: 0750, Chown: true, UID: os.Getuid(), GID: os.Getgid()}
+
+	for _, tc := range []struct {
+		w    io.Writer
+		dir  string
+		file string
+	}{
+		{&writer.DailyFileWriter{Name: "daily/logs", FileOptions: opts}, "daily", "daily/logs/" + time.Now().Format("20060102") + ".log"},
+		{&writer.SizeFileWriter{Name: "size/logs/app", MaxSize: 1024, MaxCount: 2, FileOptions: opts}, "size", "size/logs/app.0.log"},
+		{&writer.NewFileWriter{Name: "new/logs/app", FileOptions: opts}, "new", "new/logs/app"},
+	} {
+		base := t.TempDir()
+		setName(tc.w, base)
+		if _, err := fmt.Fprintln(tc.w, "line"); err != nil {
+			t.Fatalf("%T: %v", tc.w, err)
+		}
+
+		for name, want := range map[string]os.FileMode{
+			tc.dir:                0750 | os.ModeDir,
+			filepath.Dir(tc.file): 0750 | os.ModeDir,
+			tc.file:               0600,
+		} {
+			stat, err := os.Stat(filepath.Join(base, name))
+			if err != nil {
+				t.Fatalf("%T: %v", tc.w, err)
+			}
+			if stat.Mode() != want {
+				t.Errorf("%T: %s has mode %v, want %v", tc.w, name, stat.Mode(), want)
+			}
+			if sys, ok := stat.Sys().(*syscall.Stat_t); ok && (int(sys.Uid) != opts.UID || int(sys.Gid) != opts.GID) {
+				t.Errorf("%T: %s is owned by %d:%d", tc.w, name, sys.Uid, sys.Gid)
+			}
+		}
+	}
+}
+
+// setName prefixes the Name of w with dir
+func setName(w io.Writer, dir string) {
+	switch w := w.(type) {
+	case *writer.DailyFileWriter:
+		w.Name = filepath.Join(dir, w.Name)
+	case *writer.SizeFileWriter:
+		w.Name = filepath.Join(dir, w.Name)
+	case *writer.NewFileWriter:
+		w.Name = filepath.Join(dir, w.Name)
+	}
+}
+
+func TestDailyFileWriterClean(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"20200101.log", "20200102.log", "20200103.log", "keep.txt"} {
+		os.WriteFile(filepath.Join(dir, name), nil, 0644)
+	}
+
+	w := &writer.DailyFileWriter{Name: dir, MaxCount: 2}
+	fmt.Fprintln(w, "line")
+	for i := 0; i < 100 && w.Stats().FilesDeleted < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	want := fmt.Sprint([]string{"20200103.log", time.Now().Format("20060102") + ".log", writer.CurrentLink, "keep.txt"})
+	if fmt.Sprint(names) != want {
+		t.Errorf("got %v, want %v", names, want)
+	}
+}
+
+func TestSymlink(t *testing.T) {
+	dir := t.TempDir()
+
+	// a regular file is never replaced by the link
+	name := filepath.Join(dir, "app")
+	os.WriteFile(name, []byte("data"), 0644)
+	if _, err := fmt.Fprintln(&writer.NewFileWriter{Name: name}, "line"); err == nil {
+		t.Error("replaced a regular file")
+	}
+	if data, _ := os.ReadFile(name); string(data) != "data" {
+		t.Errorf("file changed to %q", data)
+	}
+	if files, _ := filepath.Glob(name + ".*.log"); len(files) != 0 {
+		t.Errorf("files left %v", files)
+	}
+
+	// the link follows rotations
+	name = filepath.Join(dir, "size")
+	w := &writer.SizeFileWriter{Name: name, MaxSize: 10, MaxCount: 3}
+	for i := 0; i < 4; i++ {
+		fmt.Fprintf(w, "line %d of the file\n", i)
+		if target, _ := os.Readlink(name); target != fmt.Sprintf("size.%d.log", i%3) {
+			t.Errorf("link points to %s after line %d", target, i)
+		}
+	}
+
+	daily := &writer.DailyFileWriter{Name: filepath.Join(dir, "daily")}
+	fmt.Fprintln(daily, "line")
+	if target, _ := os.Readlink(filepath.Join(dir, "daily", writer.CurrentLink)); target != time.Now().Format("20060102")+".log" {
+		t.Errorf("current link points to %q", target)
+	}
+
+	// DailyFileWriter keeps writing without the link
+	os.MkdirAll(filepath.Join(dir, "daily2", writer.CurrentLink), 0755)
+	var linkErrs int
+	daily = &writer.DailyFileWriter{Name: filepath.Join(dir, "daily2"), ErrorHandler: func(error) { linkErrs++ }}
+	for i := 0; i < 2; i++ {
+		if _, err := fmt.Fprintln(daily, "line"); err != nil {
+			t.Error(err)
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "daily2", time.Now().Format("20060102")+".log")); string(data) != "line\nline\n" || linkErrs != 1 {
+		t.Errorf("wrote %q with %d link errors", data, linkErrs)
+	}
+
+	name = filepath.Join(dir, "nolink")
+	fmt.Fprintln(&writer.NewFileWriter{Name: name, FileOptions: writer.FileOptions{NoSymlink: true}}, "line")
+	if _, err := os.Lstat(name); err == nil {
+		t.Error("link created with NoSymlink")
+	}
+
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".tmp") {
+			t.Errorf("temporary link %s left", e.Name())
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package writer
+
+import (
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// FileWriter writes to a single file which is rotated externally, like by
+// logrotate in create mode. It reopens Name after Reopen, or when it finds
+// Name was moved or deleted
+type FileWriter struct {
+	Name string
+	FileOptions
+
+	// CheckInterval is how often Name is checked for being moved or
+	// deleted, default is 1 second, negative disables checking
+	CheckInterval time.Duration
+
+	file     *os.File
+	syncErr  error // of closed files, returned by Sync
+	check    moveCheck
+	reopen   atomic.Bool
+	counters counters
+}
+
+// Stats returns a snapshot of counters, Rotations counts reopens
+func (w *FileWriter) Stats() Stats {
+	return w.counters.snapshot()
+}
+
+// Write implements io.Writer
+func (w *FileWriter) Write(p []byte) (n int, err error) {
+	if _, _, err := w.rotate(); err != nil {
+		return 0, err
+	}
+	return w.writeFile(p)
+}
+
+// Sync commits the current file to stable storage, and returns the error
+// of committing the files closed since the last Sync
+func (w *FileWriter) Sync() error {
+	return syncFile(w.file, &w.syncErr)
+}
+
+// Reopen makes the next write reopen Name
+func (w *FileWriter) Reopen() error {
+	w.reopen.Store(true)
+	return nil
+}
+
+// Close closes the file, the next write opens it again
+func (w *FileWriter) Close() error {
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+func (w *FileWriter) rotate() (name string, size int64, err error) {
+	reopen := w.reopen.Swap(false) || w.check.moved(w.file, w.CheckInterval)
+	if w.file != nil && !reopen {
+		return "", 0, nil
+	}
+	if w.file != nil {
+		closeFile(w.file, &w.syncErr)
+		w.file = nil
+		w.counters.rotations.Add(1)
+	}
+
+	file, err := w.openFile(w.Name, os.O_WRONLY|os.O_CREATE|os.O_APPEND)
+	if err != nil {
+		return "", 0, err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return "", 0, err
+	}
+	w.file = file
+	w.check.checked = time.Now()
+	w.counters.currentSize.Store(stat.Size())
+
+	return w.Name, stat.Size(), nil
+}
+
+func (w *FileWriter) writeFile(p []byte) (n int, err error) {
+	n, err = w.file.Write(p)
+	w.counters.currentSize.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,652 @@
+package writer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fun-think/gologger"
+)
+
+// BatchLine is a formatted line waiting to be shipped
+type BatchLine struct {
+	Time time.Time
+	Line []byte // without trailing newline
+}
+
+// BatchEncoder encodes a batch of lines as HTTP request body
+type BatchEncoder interface {
+	ContentType() string
+	Encode(lines []BatchLine) ([]byte, error)
+}
+
+// BatchChecker is implemented by encoders of APIs which report failed lines
+// in a successful response, like the Elasticsearch _bulk API
+type BatchChecker interface {
+	// Check returns the lines of the batch which failed according to the
+	// response body
+	Check(body []byte) ([]BatchFailure, error)
+}
+
+// BatchFailure is a line of a batch rejected by the endpoint
+type BatchFailure struct {
+	Index  int  // of the line in the batch
+	Retry  bool // the line may be accepted when sent again
+	Reason string
+}
+
+// HTTPWriter batches log lines and posts them to an HTTP endpoint. Write only
+// queues lines, batches are sent by a goroutine when full or every Interval.
+// Lines rejected in a successful response are retried or dropped when the
+// Encoder is a BatchChecker
+type HTTPWriter struct {
+	URL     string
+	Client  *http.Client // default is http.DefaultClient
+	Header  http.Header  // extra request headers, e.g. Authorization
+	Encoder BatchEncoder // default is NDJSONEncoder
+
+	BatchSize  int           // max lines per request, default is 1000
+	BatchBytes int           // max line bytes per request, default is 1MB
+	Interval   time.Duration // max delay of a line, default is 1s
+	Gzip       bool          // compress request body
+
+	MaxBuffered int        // max line bytes waiting to be sent, default is 8MB
+	DropPolicy  DropPolicy // applied when MaxBuffered is exceeded
+
+	MaxRetries int           // retries on 5xx, 429 and network errors, default is 5, negative disables retries
+	MinBackoff time.Duration // default is 100ms, doubled per retry
+	MaxBackoff time.Duration // default is 10s
+
+	// FlushTimeout is the max time Close retries failed batches, default is
+	// 5s. Lines still queued then are dropped
+	FlushTimeout time.Duration
+
+	// OnError is called when a batch is dropped
+	OnError func(err error)
+
+	init    sync.Once
+	mutex   sync.Mutex
+	pending []BatchLine
+	bytes   int
+	closed  bool
+	dropped atomic.Uint64
+	full    chan struct{}
+	flush   chan chan struct{}
+	quit    chan struct{}
+	done    chan struct{}
+
+	deadline time.Time // of retries once closing, used by loop only
+}
+
+// Write implements io.Writer
+func (w *HTTPWriter) Write(p []byte) (n int, err error) {
+	return w.queue(time.Now(), p)
+}
+
+// WriteEntry implements gologger.EntryWriter
+func (w *HTTPWriter) WriteEntry(entry *gologger.Entry, p []byte) (n int, err error) {
+	return w.queue(entry.Time, p)
+}
+
+func (w *HTTPWriter) queue(t time.Time, p []byte) (int, error) {
+	w.init.Do(w.start)
+
+	line := bytes.TrimRight(p, "\n")
+	line = append([]byte(nil), line...)
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.closed {
+		return 0, ErrClosed
+	}
+	limit := intOr(w.MaxBuffered, 8<<20)
+	if w.bytes+len(line) > limit {
+		if w.DropPolicy == DropNewest || len(line) > limit {
+			w.dropped.Add(1)
+			return 0, ErrBufferFull
+		}
+		n := 0
+		for w.bytes+len(line) > limit {
+			w.bytes -= len(w.pending[n].Line)
+			n++
+		}
+		w.pending = w.pending[n:]
+		w.dropped.Add(uint64(n))
+	}
+	w.pending = append(w.pending, BatchLine{Time: t, Line: line})
+	w.bytes += len(line)
+	if len(w.pending) >= intOr(w.BatchSize, 1000) || w.bytes >= intOr(w.BatchBytes, 1<<20) {
+		select {
+		case w.full <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// Dropped returns the number of lines dropped because the buffer was full,
+// or because they could not be sent
+func (w *HTTPWriter) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// Flush sends queued lines and waits for the requests to finish
+func (w *HTTPWriter) Flush() {
+	w.init.Do(w.start)
+
+	ack := make(chan struct{})
+	select {
+	case w.flush <- ack:
+		<-ack
+	case <-w.done:
+	}
+}
+
+// Reopen closes the idle connections of Client, the next batch is sent on
+// a new one, e.g. after the endpoint behind URL changed
+func (w *HTTPWriter) Reopen() error {
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	client.CloseIdleConnections()
+	return nil
+}
+
+// Close sends queued lines for up to FlushTimeout and stops the writer
+func (w *HTTPWriter) Close() error {
+	w.init.Do(w.start)
+
+	w.mutex.Lock()
+	if w.closed {
+		w.mutex.Unlock()
+		return ErrClosed
+	}
+	w.closed = true
+	w.mutex.Unlock()
+
+	close(w.quit)
+	<-w.done
+	return nil
+}
+
+func (w *HTTPWriter) start() {
+	w.full = make(chan struct{}, 1)
+	w.flush = make(chan chan struct{})
+	w.quit = make(chan struct{})
+	w.done = make(chan struct{})
+	go w.loop()
+}
+
+func (w *HTTPWriter) loop() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(durationOr(w.Interval, time.Second))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.sendPending()
+		case <-w.full:
+			w.sendPending()
+		case ack := <-w.flush:
+			w.sendPending()
+			close(ack)
+		case <-w.quit:
+			w.sendPending()
+			return
+		}
+	}
+}
+
+// sendPending sends all queued lines in batches
+func (w *HTTPWriter) sendPending() {
+	for {
+		batch := w.take()
+		if len(batch) == 0 {
+			return
+		}
+		var err error
+		if deadline := w.closeDeadline(); !deadline.IsZero() && time.Now().After(deadline) {
+			w.dropped.Add(uint64(len(batch)))
+			err = fmt.Errorf("writer: dropped batch of %d lines, %w", len(batch), ErrClosed)
+		} else {
+			err = w.send(batch)
+		}
+		if err != nil && w.OnError != nil {
+			w.OnError(err)
+		}
+	}
+}
+
+// take removes a batch from the queue
+func (w *HTTPWriter) take() []BatchLine {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	maxLines := intOr(w.BatchSize, 1000)
+	maxBytes := intOr(w.BatchBytes, 1<<20)
+
+	n, size := 0, 0
+	for n < len(w.pending) && n < maxLines {
+		if n > 0 && size+len(w.pending[n].Line) > maxBytes {
+			break
+		}
+		size += len(w.pending[n].Line)
+		n++
+	}
+	batch := w.pending[:n:n]
+	w.pending = w.pending[n:]
+	w.bytes -= size
+	return batch
+}
+
+// send posts batch, retrying with backoff on 5xx, 429 and network errors,
+// and the lines a BatchChecker reports as retryable
+func (w *HTTPWriter) send(batch []BatchLine) error {
+	encoder := w.Encoder
+	if encoder == nil {
+		encoder = new(NDJSONEncoder)
+	}
+	checker, _ := encoder.(BatchChecker)
+
+	backoff := durationOr(w.MinBackoff, 100*time.Millisecond)
+	maxBackoff := durationOr(w.MaxBackoff, 10*time.Second)
+	retries := w.MaxRetries
+	if retries == 0 {
+		retries = 5
+	}
+
+	var errs []error
+	for i := 0; ; i++ {
+		body, err := w.encode(encoder, batch)
+		if err != nil {
+			w.dropped.Add(uint64(len(batch)))
+			return errors.Join(append(errs, err)...)
+		}
+		retryAfter, resp, err := w.post(encoder.ContentType(), body, checker != nil)
+		if err == nil && checker != nil {
+			var rejected error
+			batch, rejected = w.check(checker, batch, resp)
+			if rejected != nil {
+				errs = append(errs, rejected)
+			}
+			if len(batch) > 0 {
+				err = fmt.Errorf("%d lines failed", len(batch))
+			}
+		}
+		if err == nil {
+			return errors.Join(errs...)
+		}
+		delay := backoff
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+		if retryAfter < 0 || i >= retries || !w.sleep(delay) {
+			w.dropped.Add(uint64(len(batch)))
+			return errors.Join(append(errs, fmt.Errorf("writer: dropped batch of %d lines, %w", len(batch), err))...)
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// closeDeadline returns the time until which batches are retried once
+// closing, zero before Close
+func (w *HTTPWriter) closeDeadline() time.Time {
+	if w.deadline.IsZero() {
+		select {
+		case <-w.quit:
+			w.deadline = time.Now().Add(durationOr(w.FlushTimeout, 5*time.Second))
+		default:
+		}
+	}
+	return w.deadline
+}
+
+// sleep waits for d before a retry, or until Close is called. It returns
+// false if the retry would pass the flush deadline once closing
+func (w *HTTPWriter) sleep(d time.Duration) bool {
+	if deadline := w.closeDeadline(); !deadline.IsZero() {
+		if time.Until(deadline) < d {
+			return false
+		}
+		time.Sleep(d)
+		return true
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-w.quit:
+	}
+	return true
+}
+
+// encode returns batch encoded as request body
+func (w *HTTPWriter) encode(encoder BatchEncoder, batch []BatchLine) ([]byte, error) {
+	body, err := encoder.Encode(batch)
+	if err != nil || !w.Gzip {
+		return body, err
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(body)
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// check returns the lines of batch to retry according to the response body,
+// the error reports the dropped ones
+func (w *HTTPWriter) check(checker BatchChecker, batch []BatchLine, resp []byte) (retry []BatchLine, err error) {
+	failures, err := checker.Check(resp)
+	if err != nil {
+		return nil, fmt.Errorf("writer: batch of %d lines sent, invalid response, %w", len(batch), err)
+	}
+
+	var dropped int
+	var reason string
+	for _, f := range failures {
+		if f.Index < 0 || f.Index >= len(batch) {
+			continue
+		}
+		if f.Retry {
+			retry = append(retry, batch[f.Index])
+			continue
+		}
+		if dropped == 0 {
+			reason = f.Reason
+		}
+		dropped++
+	}
+	if dropped > 0 {
+		w.dropped.Add(uint64(dropped))
+		err = fmt.Errorf("writer: dropped %d lines rejected by %s, %s", dropped, w.URL, reason)
+	}
+	return retry, err
+}
+
+// post sends body once, retryAfter is negative if the request must not be
+// retried. The response body is returned if read
+func (w *HTTPWriter) post(contentType string, body []byte, read bool) (retryAfter time.Duration, resp []byte, err error) {
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return -1, nil, err
+	}
+	for k, v := range w.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", contentType)
+	if w.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	if read && res.StatusCode < 300 {
+		resp, err = io.ReadAll(io.LimitReader(res.Body, 64<<20))
+	} else {
+		io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+	}
+	res.Body.Close()
+
+	switch {
+	case res.StatusCode < 300:
+		return 0, resp, err
+	case res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500:
+		seconds, _ := strconv.Atoi(res.Header.Get("Retry-After"))
+		return time.Duration(seconds) * time.Second, nil, errors.New(res.Status)
+	default:
+		return -1, nil, errors.New(res.Status)
+	}
+}
+
+func intOr(n, def int) int {
+	if n <= 0 {
+		return def
+	}
+	return n
+}
+
+// jsonObject returns line as a json object, lines which are not json
+// objects, e.g. from TextFormat, are wrapped as {"msg": line}
+func jsonObject(line []byte) (map[string]any, bool) {
+	var obj map[string]any
+	if json.Unmarshal(line, &obj) == nil {
+		return obj, true
+	}
+	return map[string]any{"msg": string(line)}, false
+}
+
+// NDJSONEncoder encodes lines as newline delimited json
+type NDJSONEncoder struct{}
+
+// ContentType implements BatchEncoder
+func (e *NDJSONEncoder) ContentType() string {
+	return "application/x-ndjson"
+}
+
+// Encode implements BatchEncoder
+func (e *NDJSONEncoder) Encode(lines []BatchLine) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, l := range lines {
+		if json.Valid(l.Line) && bytes.HasPrefix(l.Line, []byte("{")) {
+			buf.Write(l.Line)
+			buf.WriteByte('\n')
+			continue
+		}
+		data, err := marshalNoEscape(map[string]any{"msg": string(l.Line)})
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+	}
+	return buf.Bytes(), nil
+}
+
+// ElasticsearchEncoder encodes lines for the Elasticsearch _bulk API
+type ElasticsearchEncoder struct {
+	Index string
+
+	// IndexTimeLayout formats the line time appended to Index, e.g. "-2006.01.02"
+	IndexTimeLayout string
+
+	// TimeField is set to the line time when missing, default is "@timestamp"
+	TimeField string
+}
+
+// ContentType implements BatchEncoder
+func (e *ElasticsearchEncoder) ContentType() string {
+	return "application/x-ndjson"
+}
+
+// Encode implements BatchEncoder
+func (e *ElasticsearchEncoder) Encode(lines []BatchLine) ([]byte, error) {
+	timeField := e.TimeField
+	if timeField == "" {
+		timeField = "@timestamp"
+	}
+
+	var buf bytes.Buffer
+	for _, l := range lines {
+		index := e.Index
+		if e.IndexTimeLayout != "" {
+			index += l.Time.Format(e.IndexTimeLayout)
+		}
+		action, err := marshalNoEscape(map[string]any{
+			"index": map[string]string{"_index": index},
+		})
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(action)
+
+		doc, _ := jsonObject(l.Line)
+		if _, ok := doc[timeField]; !ok {
+			doc[timeField] = l.Time.Format(time.RFC3339Nano)
+		}
+		data, err := marshalNoEscape(doc)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+	}
+	return buf.Bytes(), nil
+}
+
+// Check implements BatchChecker for the items of a _bulk response, items
+// rejected with 429 or 5xx are retried
+func (e *ElasticsearchEncoder) Check(body []byte) ([]BatchFailure, error) {
+	var resp struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int `json:"status"`
+			Error  struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	if !resp.Errors {
+		return nil, nil
+	}
+
+	var failures []BatchFailure
+	for i, item := range resp.Items {
+		for _, result := range item {
+			if result.Status < 300 {
+				continue
+			}
+			failures = append(failures, BatchFailure{
+				Index:  i,
+				Retry:  result.Status == http.StatusTooManyRequests || result.Status >= 500,
+				Reason: fmt.Sprintf("%d %s: %s", result.Status, result.Error.Type, result.Error.Reason),
+			})
+		}
+	}
+	return failures, nil
+}
+
+// LokiEncoder encodes lines for the Loki push API, lines are grouped into
+// streams by labels
+type LokiEncoder struct {
+	Labels map[string]string // static labels of every stream
+
+	// LabelFields are fields of JSONFormat lines used as labels, e.g. level, app, host
+	LabelFields []string
+}
+
+// ContentType implements BatchEncoder
+func (e *LokiEncoder) ContentType() string {
+	return "application/json"
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// Encode implements BatchEncoder
+func (e *LokiEncoder) Encode(lines []BatchLine) ([]byte, error) {
+	streams := map[string]*lokiStream{}
+	var keys []string
+
+	for _, l := range lines {
+		labels := make(map[string]string, len(e.Labels)+len(e.LabelFields))
+		for k, v := range e.Labels {
+			labels[k] = v
+		}
+		if len(e.LabelFields) > 0 {
+			if obj, ok := jsonObject(l.Line); ok {
+				for _, field := range e.LabelFields {
+					if v, ok := obj[field]; ok {
+						labels[lokiLabelName(field)] = fmt.Sprint(v)
+					}
+				}
+			}
+		}
+
+		key := lokiStreamKey(labels)
+		stream, ok := streams[key]
+		if !ok {
+			stream = &lokiStream{Stream: labels}
+			streams[key] = stream
+			keys = append(keys, key)
+		}
+		stream.Values = append(stream.Values, [2]string{strconv.FormatInt(l.Time.UnixNano(), 10), string(l.Line)})
+	}
+
+	payload := struct {
+		Streams []*lokiStream `json:"streams"`
+	}{}
+	for _, key := range keys {
+		payload.Streams = append(payload.Streams, streams[key])
+	}
+	return marshalNoEscape(payload)
+}
+
+// lokiStreamKey returns labels in a canonical form
+func lokiStreamKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, k := range keys {
+		sb.WriteString(strconv.Quote(k))
+		sb.WriteByte('=')
+		sb.WriteString(strconv.Quote(labels[k]))
+		sb.WriteByte(',')
+	}
+	return sb.String()
+}
+
+// lokiLabelName replaces characters invalid in label names with '_'
+func lokiLabelName(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || i > 0 && c >= '0' && c <= '9') {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+// marshalNoEscape returns v as json followed by a newline, without escaping html
+func marshalNoEscape(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
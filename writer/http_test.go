@@ -0,0 +1,244 @@
+package writer_test
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fun-think/gologger"
+	"github.com/fun-think/gologger/format"
+	"github.com/fun-think/gologger/writer"
+)
+
+// collector records request bodies, failing the first requests with fails
+type collector struct {
+	mutex  sync.Mutex
+	fails  []int
+	bodies []string
+	types  []string
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if len(c.fails) > 0 {
+		w.WriteHeader(c.fails[0])
+		c.fails = c.fails[1:]
+		return
+	}
+
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body = zr
+	}
+	data, _ := io.ReadAll(body)
+	c.bodies = append(c.bodies, string(data))
+	c.types = append(c.types, r.Header.Get("Content-Type"))
+}
+
+func (c *collector) received() []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]string(nil), c.bodies...)
+}
+
+func TestHTTPWriterLoki(t *testing.T) {
+	c := &collector{fails: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	w := &writer.HTTPWriter{
+		URL:        server.URL,
+		Gzip:       true,
+		MinBackoff: time.Millisecond,
+		Encoder: &writer.LokiEncoder{
+			Labels:      map[string]string{"job": "test"},
+			LabelFields: []string{"level", "app"},
+		},
+	}
+	logger := &gologger.Logger{Level: gologger.INFO, Format: &format.JSONFormat{AppName: "app"}, Output: w}
+	logger.Info("one")
+	logger.Error("two")
+	logger.Info("three")
+	w.Close()
+
+	bodies := c.received()
+	if len(bodies) != 1 || c.types[0] != "application/json" {
+		t.Fatalf("got %d requests: %q", len(bodies), bodies)
+	}
+	var payload struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal([]byte(bodies[0]), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if len(payload.Streams) != 2 {
+		t.Fatalf("got %d streams, want 2", len(payload.Streams))
+	}
+	info, errs := payload.Streams[0], payload.Streams[1]
+	if info.Stream["level"] != "INFO" || info.Stream["app"] != "app" || info.Stream["job"] != "test" || len(info.Values) != 2 {
+		t.Errorf("unexpected INFO stream %+v", info)
+	}
+	if errs.Stream["level"] != "ERROR" || len(errs.Values) != 1 || !strings.Contains(errs.Values[0][1], `"msg":"two"`) {
+		t.Errorf("unexpected ERROR stream %+v", errs)
+	}
+}
+
+func TestHTTPWriterElasticsearch(t *testing.T) {
+	c := new(collector)
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	w := &writer.HTTPWriter{
+		URL:       server.URL,
+		BatchSize: 2,
+		Interval:  time.Hour,
+		Encoder:   &writer.ElasticsearchEncoder{Index: "logs", IndexTimeLayout: "-2006"},
+	}
+	defer w.Close()
+	w.Write([]byte(`{"msg":"json"}` + "\n"))
+	w.Write([]byte("plain text\n"))
+	w.Write([]byte("pending\n"))
+	w.Flush()
+
+	bodies := c.received()
+	if len(bodies) != 2 {
+		t.Fatalf("got %d requests: %q", len(bodies), bodies)
+	}
+	lines := strings.Split(strings.TrimSuffix(bodies[0], "\n"), "\n")
+	index := `{"index":{"_index":"logs-` + time.Now().Format("2006") + `"}}`
+	if len(lines) != 4 || lines[0] != index || lines[2] != index ||
+		!strings.HasPrefix(lines[1], `{"@timestamp":`) || !strings.Contains(lines[3], `"msg":"plain text"`) {
+		t.Errorf("unexpected bulk body:\n%s", bodies[0])
+	}
+	if c.types[0] != "application/x-ndjson" {
+		t.Errorf("content type %q", c.types[0])
+	}
+}
+
+func TestHTTPWriterDrop(t *testing.T) {
+	c := &collector{fails: []int{http.StatusBadRequest}}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	var dropped error
+	w := &writer.HTTPWriter{URL: server.URL, OnError: func(err error) { dropped = err }}
+	w.Write([]byte("rejected\n"))
+	w.Close()
+
+	if dropped == nil || len(c.received()) != 0 {
+		t.Errorf("4xx should drop without retry, error %v", dropped)
+	}
+}
+
+func TestHTTPWriterRetries(t *testing.T) {
+	var mutex sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests++
+		mutex.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	w := &writer.HTTPWriter{URL: server.URL, MaxRetries: -1, MinBackoff: time.Hour}
+	w.Write([]byte("line\n"))
+	w.Close()
+	if requests != 1 || w.Dropped() != 1 {
+		t.Errorf("negative MaxRetries: %d requests, dropped %d", requests, w.Dropped())
+	}
+
+	// Close interrupts the backoff and gives up after FlushTimeout
+	w = &writer.HTTPWriter{URL: server.URL, Interval: time.Millisecond, MaxRetries: 100, MinBackoff: 20 * time.Millisecond, FlushTimeout: 100 * time.Millisecond}
+	w.Write([]byte("line\n"))
+	time.Sleep(10 * time.Millisecond)
+	w.Write([]byte("line\n"))
+	start := time.Now()
+	w.Close()
+	if elapsed := time.Since(start); elapsed > time.Second || w.Dropped() != 2 {
+		t.Errorf("Close took %v, dropped %d", elapsed, w.Dropped())
+	}
+}
+
+func TestHTTPWriterBuffer(t *testing.T) {
+	// no batch is sent before Close
+	server := httptest.NewServer(new(collector))
+	defer server.Close()
+
+	for _, policy := range []writer.DropPolicy{writer.DropNewest, writer.DropOldest} {
+		w := &writer.HTTPWriter{URL: server.URL, Interval: time.Hour, MaxBuffered: 20, DropPolicy: policy}
+		var errs int
+		for i := 0; i < 5; i++ {
+			if _, err := w.Write([]byte("12345678\n")); err != nil {
+				errs++
+			}
+		}
+		if w.Dropped() != 3 || (policy == writer.DropNewest) != (errs == 3) {
+			t.Errorf("policy %d: dropped %d, %d write errors", policy, w.Dropped(), errs)
+		}
+		w.Close()
+	}
+}
+
+func TestHTTPWriterElasticsearchErrors(t *testing.T) {
+	var mutex sync.Mutex
+	var requests []int // lines per request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		lines := strings.Count(string(data), "\n") / 2
+
+		mutex.Lock()
+		requests = append(requests, lines)
+		first := len(requests) == 1
+		mutex.Unlock()
+
+		// the first line is rejected, the second one overloaded once
+		items := make([]string, lines)
+		for i := range items {
+			items[i] = `{"index":{"status":201}}`
+		}
+		if first {
+			items[0] = `{"index":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"bad field"}}}`
+			items[1] = `{"index":{"status":429,"error":{"type":"es_rejected_execution_exception","reason":"queue full"}}}`
+		}
+		fmt.Fprintf(w, `{"errors":%t,"items":[%s]}`, first, strings.Join(items, ","))
+	}))
+	defer server.Close()
+
+	var errs []error
+	w := &writer.HTTPWriter{
+		URL:        server.URL,
+		Interval:   time.Hour,
+		MinBackoff: time.Millisecond,
+		Encoder:    &writer.ElasticsearchEncoder{Index: "logs"},
+		OnError:    func(err error) { errs = append(errs, err) },
+	}
+	for i := 0; i < 3; i++ {
+		fmt.Fprintf(w, "line %d\n", i)
+	}
+	w.Close()
+
+	if fmt.Sprint(requests) != "[3 1]" || w.Dropped() != 1 {
+		t.Errorf("requests %v, dropped %d", requests, w.Dropped())
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "dropped 1 lines") || !strings.Contains(errs[0].Error(), "bad field") {
+		t.Errorf("errors %v", errs)
+	}
+}
@@ -0,0 +1,156 @@
+package writer
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/fun-think/gologger"
+)
+
+// JournaldSocket is the socket of the journald native protocol
+const JournaldSocket = "/run/systemd/journal/socket"
+
+// JournaldWriter sends log lines to systemd-journald using its native protocol.
+// Entry fields are sent as uppercase journal fields, prefixed with F_ when
+// named like a field set by the writer, MESSAGE, PRIORITY, SYSLOG_* or CODE_*
+type JournaldWriter struct {
+	Socket     string // default is JournaldSocket
+	Identifier string // SYSLOG_IDENTIFIER, default is the program name
+
+	mutex sync.Mutex
+	conn  *net.UnixConn
+}
+
+// Write implements io.Writer, lines are sent with priority info
+func (w *JournaldWriter) Write(p []byte) (n int, err error) {
+	if _, err := w.send(w.message(6, p, nil)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteEntry implements gologger.EntryWriter
+func (w *JournaldWriter) WriteEntry(entry *gologger.Entry, p []byte) (n int, err error) {
+	if _, err := w.send(w.message(SyslogSeverity(entry.Level), p, entry)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close closes the connection
+func (w *JournaldWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// Reopen closes the connection, the next write connects again
+func (w *JournaldWriter) Reopen() error {
+	return w.Close()
+}
+
+// message returns p encoded as journal fields
+func (w *JournaldWriter) message(priority int, p []byte, entry *gologger.Entry) []byte {
+	identifier := w.Identifier
+	if identifier == "" {
+		identifier = filepath.Base(os.Args[0])
+	}
+
+	var b []byte
+	b = appendJournalField(b, "MESSAGE", strings.TrimRight(string(p), "\n"))
+	b = appendJournalField(b, "PRIORITY", strconv.Itoa(priority))
+	b = appendJournalField(b, "SYSLOG_IDENTIFIER", identifier)
+	if entry == nil {
+		return b
+	}
+
+	if entry.Caller.Defined() {
+		b = appendJournalField(b, "CODE_FILE", entry.Caller.File)
+		b = appendJournalField(b, "CODE_LINE", strconv.Itoa(entry.Caller.Line))
+		b = appendJournalField(b, "CODE_FUNC", entry.Caller.Function)
+	}
+	for _, k := range entry.Fields.Keys() {
+		b = appendJournalField(b, journalFieldName(k), fmt.Sprint(entry.Fields[k]))
+	}
+	return b
+}
+
+func (w *JournaldWriter) send(msg []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.conn == nil {
+		socket := w.Socket
+		if socket == "" {
+			socket = JournaldSocket
+		}
+		conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+		if err != nil {
+			return 0, err
+		}
+		w.conn = conn
+	}
+
+	_, err := w.conn.Write(msg)
+	if errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS) {
+		// too large for a datagram, pass a file descriptor instead
+		err = sendJournalFile(w.conn, msg)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(msg), nil
+}
+
+// appendJournalField appends KEY=value, or the binary form if value contains a newline
+func appendJournalField(b []byte, key, value string) []byte {
+	b = append(b, key...)
+	if !strings.Contains(value, "\n") {
+		b = append(b, '=')
+		b = append(b, value...)
+		return append(b, '\n')
+	}
+	b = append(b, '\n')
+	b = binary.LittleEndian.AppendUint64(b, uint64(len(value)))
+	b = append(b, value...)
+	return append(b, '\n')
+}
+
+// journalFieldName returns k as a valid journal field name, uppercase letters,
+// digits and underscores, not starting with an underscore or digit
+func journalFieldName(k string) string {
+	name := []byte(strings.ToUpper(k))
+	for i, c := range name {
+		if !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			name[i] = '_'
+		}
+	}
+	s := strings.TrimLeft(string(name), "_")
+	if s == "" || s[0] <= '9' || isReservedJournalField(s) {
+		s = "F_" + s
+	}
+	if len(s) > 64 {
+		s = s[:64]
+	}
+	return s
+}
+
+// isReservedJournalField indicates whether name is set by JournaldWriter
+func isReservedJournalField(name string) bool {
+	return name == "MESSAGE" || name == "PRIORITY" ||
+		strings.HasPrefix(name, "SYSLOG_") || strings.HasPrefix(name, "CODE_")
+}
@@ -0,0 +1,91 @@
+package writer
+
+import (
+	"net"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// memfdCreateTrap is the memfd_create syscall number, missing from syscall on most architectures
+var memfdCreateTrap = map[string]uintptr{
+	"386": 356, "amd64": 319, "arm": 385, "arm64": 279, "loong64": 279, "riscv64": 279,
+	"mips": 4354, "mipsle": 4354, "mips64": 5314, "mips64le": 5314,
+	"ppc64": 360, "ppc64le": 360, "s390x": 350,
+}[runtime.GOARCH]
+
+const (
+	mfdCloexec       = 0x1
+	mfdAllowSealing  = 0x2
+	fAddSeals        = 1033
+	fSealAll         = 0xf // F_SEAL_SEAL | F_SEAL_SHRINK | F_SEAL_GROW | F_SEAL_WRITE
+	journalTmpfsPath = "/dev/shm"
+)
+
+// sendJournalFile passes msg to journald in a sealed memfd, or an unlinked
+// temp file if memfd is not available
+func sendJournalFile(conn *net.UnixConn, msg []byte) error {
+	file, err := memfd(msg)
+	if err != nil {
+		file, err = unlinkedTemp(msg)
+		if err != nil {
+			return err
+		}
+	}
+	defer file.Close()
+
+	// WriteMsgUnix refuses connected datagram sockets
+	rc, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	rights := syscall.UnixRights(int(file.Fd()))
+	werr := rc.Write(func(fd uintptr) bool {
+		err = syscall.Sendmsg(int(fd), nil, rights, nil, 0)
+		return err != syscall.EAGAIN
+	})
+	if werr != nil {
+		return werr
+	}
+	return err
+}
+
+func memfd(msg []byte) (*os.File, error) {
+	if memfdCreateTrap == 0 {
+		return nil, syscall.ENOSYS
+	}
+	name, _ := syscall.BytePtrFromString("journal-entry")
+	fd, _, errno := syscall.Syscall(memfdCreateTrap, uintptr(unsafe.Pointer(name)), mfdCloexec|mfdAllowSealing, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	file := os.NewFile(fd, "journal-entry")
+
+	if _, err := file.Write(msg); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_FCNTL, fd, fAddSeals, fSealAll); errno != 0 {
+		file.Close()
+		return nil, errno
+	}
+	return file, nil
+}
+
+func unlinkedTemp(msg []byte) (*os.File, error) {
+	file, err := os.CreateTemp(journalTmpfsPath, "journal-")
+	if err != nil {
+		file, err = os.CreateTemp("", "journal-")
+		if err != nil {
+			return nil, err
+		}
+	}
+	os.Remove(file.Name())
+
+	if _, err := file.Write(msg); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
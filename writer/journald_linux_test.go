@@ -0,0 +1,43 @@
+package writer_test
+
+import (
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/fun-think/gologger/writer"
+)
+
+func TestJournaldLargeEntry(t *testing.T) {
+	conn, name := listenJournal(t)
+
+	w := &writer.JournaldWriter{Socket: name}
+	defer w.Close()
+	msg := strings.Repeat("x", 1<<20)
+	if n, err := w.Write([]byte(msg)); err != nil || n != len(msg) {
+		t.Fatalf("wrote %d bytes, %v", n, err)
+	}
+
+	oob := make([]byte, syscall.CmsgSpace(4))
+	_, oobn, _, _, err := conn.ReadMsgUnix(nil, oob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("no control message: %v", err)
+	}
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := os.NewFile(uintptr(fds[0]), "entry")
+	defer file.Close()
+
+	data := make([]byte, 2<<20)
+	n, _ := file.ReadAt(data, 0)
+	if fields := parseJournal(t, data[:n]); fields["MESSAGE"] != msg {
+		t.Errorf("MESSAGE has %d bytes, want %d", len(fields["MESSAGE"]), len(msg))
+	}
+}
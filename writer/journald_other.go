@@ -0,0 +1,13 @@
+//go:build !linux
+
+package writer
+
+import (
+	"errors"
+	"net"
+)
+
+// sendJournalFile is not supported, journald only runs on linux
+func sendJournalFile(conn *net.UnixConn, msg []byte) error {
+	return errors.New("journal entry too large")
+}
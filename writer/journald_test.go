@@ -0,0 +1,83 @@
+package writer_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fun-think/gologger"
+	"github.com/fun-think/gologger/writer"
+)
+
+// parseJournal decodes fields of the journald native protocol
+func parseJournal(t *testing.T, b []byte) map[string]string {
+	t.Helper()
+	fields := map[string]string{}
+	for len(b) > 0 {
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 {
+			t.Fatalf("truncated field %q", b)
+		}
+		line := string(b[:i])
+		b = b[i+1:]
+		if k, v, ok := strings.Cut(line, "="); ok {
+			fields[k] = v
+			continue
+		}
+		size := binary.LittleEndian.Uint64(b)
+		fields[line] = string(b[8 : 8+size])
+		b = b[8+size+1:]
+	}
+	return fields
+}
+
+func listenJournal(t *testing.T) (*net.UnixConn, string) {
+	name := filepath.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: name, Net: "unixgram"})
+	if err != nil {
+		t.Skip(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn, name
+}
+
+func TestJournald(t *testing.T) {
+	conn, name := listenJournal(t)
+
+	w := &writer.JournaldWriter{Socket: name, Identifier: "app"}
+	defer w.Close()
+	logger := &gologger.Logger{Level: gologger.INFO, Format: msgFormat{}, Output: w}
+	logger.WithFields(gologger.Fields{"user-id": 7, "_trusted": "x", "message": "forged", "priority": 0, "code_line": 1}).Warn("two\nlines")
+
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := parseJournal(t, buf[:n])
+	want := map[string]string{
+		"MESSAGE":           "two\nlines",
+		"PRIORITY":          "4",
+		"SYSLOG_IDENTIFIER": "app",
+		"CODE_FILE":         fields["CODE_FILE"],
+		"CODE_FUNC":         "github.com/fun-think/gologger/writer_test.TestJournald",
+		"USER_ID":           "7",
+		"TRUSTED":           "x",
+		"F_MESSAGE":         "forged",
+		"F_PRIORITY":        "0",
+		"F_CODE_LINE":       "1",
+	}
+	if !strings.HasSuffix(fields["CODE_FILE"], "journald_test.go") || fields["CODE_LINE"] == "" {
+		t.Errorf("unexpected caller %v", fields)
+	}
+	for k, v := range want {
+		if fields[k] != v {
+			t.Errorf("%s = %q, want %q", k, fields[k], v)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package writer
+
+import "errors"
+
+// lockFile is not supported without flock
+func lockFile(name string, opts FileOptions) (unlock func(), err error) {
+	return nil, errors.New("file locking is not supported on this platform")
+}
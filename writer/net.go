@@ -0,0 +1,481 @@
+package writer
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Framing separates messages on a stream connection
+type Framing int
+
+// These are the supported framings
+const (
+	// NewlineFraming terminates every message with '\n', unless it ends with one
+	NewlineFraming Framing = iota
+	// LengthPrefixFraming prefixes every message with its length as 4 byte big endian
+	LengthPrefixFraming
+)
+
+// DropPolicy decides which messages are dropped when the buffer is full
+type DropPolicy int
+
+// These are the supported drop policies
+const (
+	DropNewest DropPolicy = iota
+	DropOldest
+)
+
+// ErrBufferFull is returned by NetWriter.Write when a message is dropped
+var ErrBufferFull = errors.New("writer: buffer full, message dropped")
+
+// ErrClosed is returned by writes after Close
+var ErrClosed = errors.New("writer: closed")
+
+// NetWriter sends log lines to a remote collector over TCP, TLS or unix
+// sockets. Write only buffers lines, a goroutine delivers them and reconnects
+// with exponential backoff
+type NetWriter struct {
+	Network   string // tcp or unix
+	Addr      string
+	TLSConfig *tls.Config // connect with TLS if set
+	Framing   Framing
+
+	MaxBuffered int64      // max bytes waiting for delivery, default is 8MB
+	DropPolicy  DropPolicy // applied when MaxBuffered is exceeded
+
+	// BufferFile keeps waiting messages in this file instead of memory,
+	// messages left by a previous process are sent first
+	BufferFile string
+
+	MinBackoff   time.Duration // first reconnect delay, default is 100ms
+	MaxBackoff   time.Duration // max reconnect delay, default is 30s
+	WriteTimeout time.Duration // default is 10s
+	FlushTimeout time.Duration // max time Close waits for delivery, default is 5s
+
+	init    sync.Once
+	initErr error
+	mutex   sync.Mutex
+	cond    *sync.Cond
+	buffer  netBuffer
+	closing bool
+	stop    chan struct{} // closed by Close to interrupt backoff
+	done    chan struct{}
+	dropped atomic.Uint64
+	redial  atomic.Bool
+}
+
+// Write implements io.Writer
+func (w *NetWriter) Write(p []byte) (n int, err error) {
+	w.init.Do(w.start)
+	if w.initErr != nil {
+		return 0, w.initErr
+	}
+
+	frame := w.frame(p)
+	limit := w.MaxBuffered
+	if limit <= 0 {
+		limit = 8 << 20
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.closing {
+		return 0, ErrClosed
+	}
+	if w.buffer.size()+int64(len(frame)) > limit {
+		if w.DropPolicy == DropNewest || int64(len(frame)) > limit {
+			w.dropped.Add(1)
+			return 0, ErrBufferFull
+		}
+		for w.buffer.size()+int64(len(frame)) > limit {
+			w.buffer.pop()
+			w.dropped.Add(1)
+		}
+	}
+	if err := w.buffer.push(frame); err != nil {
+		return 0, err
+	}
+	w.cond.Signal()
+	return len(p), nil
+}
+
+// Dropped returns the number of messages dropped because the buffer was full
+func (w *NetWriter) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// Buffered returns the bytes waiting for delivery
+func (w *NetWriter) Buffered() int64 {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.buffer == nil {
+		return 0
+	}
+	return w.buffer.size()
+}
+
+// Reopen drops the connection, the next message is sent on a new one, e.g.
+// after the collector behind Addr changed
+func (w *NetWriter) Reopen() error {
+	w.redial.Store(true)
+	return nil
+}
+
+// Close delivers buffered messages for up to FlushTimeout and closes the connection
+func (w *NetWriter) Close() error {
+	w.init.Do(w.start)
+	if w.initErr != nil {
+		return w.initErr
+	}
+
+	w.mutex.Lock()
+	if w.closing {
+		w.mutex.Unlock()
+		return ErrClosed
+	}
+	w.closing = true
+	w.cond.Broadcast()
+	close(w.stop)
+	w.mutex.Unlock()
+
+	<-w.done
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	var err error
+	if w.buffer.size() > 0 {
+		err = errors.New("writer: messages not delivered before close")
+	}
+	w.buffer.close()
+	return err
+}
+
+func (w *NetWriter) start() {
+	w.cond = sync.NewCond(&w.mutex)
+	w.stop = make(chan struct{})
+	w.done = make(chan struct{})
+	if w.BufferFile != "" {
+		w.buffer, w.initErr = newFileBuffer(w.BufferFile)
+		if w.initErr != nil {
+			return
+		}
+	} else {
+		w.buffer = new(memoryBuffer)
+	}
+	go w.deliver()
+}
+
+func (w *NetWriter) frame(p []byte) []byte {
+	if w.Framing == LengthPrefixFraming {
+		frame := make([]byte, 4, 4+len(p))
+		binary.BigEndian.PutUint32(frame, uint32(len(p)))
+		return append(frame, p...)
+	}
+	frame := make([]byte, len(p), len(p)+1)
+	copy(frame, p)
+	if len(p) == 0 || p[len(p)-1] != '\n' {
+		frame = append(frame, '\n')
+	}
+	return frame
+}
+
+// deliver writes buffered frames to the connection until closed
+func (w *NetWriter) deliver() {
+	defer close(w.done)
+
+	var conn net.Conn
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+
+	minBackoff := durationOr(w.MinBackoff, 100*time.Millisecond)
+	maxBackoff := durationOr(w.MaxBackoff, 30*time.Second)
+	writeTimeout := durationOr(w.WriteTimeout, 10*time.Second)
+	backoff := minBackoff
+	var deadline time.Time // flush deadline once closing
+
+	for {
+		w.mutex.Lock()
+		for w.buffer.size() == 0 && !w.closing {
+			w.cond.Wait()
+		}
+		if w.closing && deadline.IsZero() {
+			deadline = time.Now().Add(durationOr(w.FlushTimeout, 5*time.Second))
+		}
+		if w.buffer.size() == 0 || (!deadline.IsZero() && time.Now().After(deadline)) {
+			w.mutex.Unlock()
+			return
+		}
+		frame, err := w.buffer.peek()
+		w.mutex.Unlock()
+		if err != nil {
+			w.mutex.Lock()
+			w.buffer.pop()
+			w.mutex.Unlock()
+			continue
+		}
+
+		if w.redial.Swap(false) && conn != nil {
+			conn.Close()
+			conn = nil
+		}
+		if conn == nil {
+			conn, err = w.dial(writeTimeout)
+			if err != nil {
+				w.sleep(backoff, deadline)
+				backoff *= 2
+				if backoff > maxBackoff {
+					backoff = maxBackoff
+				}
+				continue
+			}
+			backoff = minBackoff
+		}
+
+		conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if _, err := conn.Write(frame); err != nil {
+			conn.Close()
+			conn = nil
+			continue
+		}
+
+		w.mutex.Lock()
+		w.buffer.pop()
+		w.mutex.Unlock()
+	}
+}
+
+func (w *NetWriter) dial(timeout time.Duration) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	if w.TLSConfig != nil {
+		return tls.DialWithDialer(dialer, w.Network, w.Addr, w.TLSConfig)
+	}
+	return dialer.Dial(w.Network, w.Addr)
+}
+
+// sleep waits for d, until Close is called, or until the flush deadline
+// once closing
+func (w *NetWriter) sleep(d time.Duration, deadline time.Time) {
+	var stop <-chan struct{}
+	if deadline.IsZero() {
+		stop = w.stop
+	} else if left := time.Until(deadline); left < d {
+		d = left
+	}
+	if d <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-stop:
+	}
+}
+
+func durationOr(d, def time.Duration) time.Duration {
+	if d <= 0 {
+		return def
+	}
+	return d
+}
+
+// netBuffer is a queue of frames, guarded by NetWriter.mutex
+type netBuffer interface {
+	push(frame []byte) error
+	peek() ([]byte, error)
+	pop()
+	size() int64
+	close() error
+}
+
+// memoryBuffer keeps frames in memory
+type memoryBuffer struct {
+	frames [][]byte
+	bytes  int64
+}
+
+func (b *memoryBuffer) push(frame []byte) error {
+	b.frames = append(b.frames, frame)
+	b.bytes += int64(len(frame))
+	return nil
+}
+
+func (b *memoryBuffer) peek() ([]byte, error) {
+	return b.frames[0], nil
+}
+
+func (b *memoryBuffer) pop() {
+	b.bytes -= int64(len(b.frames[0]))
+	b.frames[0] = nil
+	b.frames = b.frames[1:]
+}
+
+func (b *memoryBuffer) size() int64 {
+	return b.bytes
+}
+
+func (b *memoryBuffer) close() error {
+	b.frames = nil
+	b.bytes = 0
+	return nil
+}
+
+// fileBufferCompact is the offset of the first frame from which the frames
+// of a fileBuffer are moved to the start of the file
+const fileBufferCompact = 1 << 20
+
+// fileBufferPopped marks the length of a frame removed from a fileBuffer
+const fileBufferPopped = 1 << 31
+
+// errFileBufferCorrupt is returned by fileBuffer.peek for a truncated frame
+var errFileBufferCorrupt = errors.New("writer: buffer file truncated")
+
+// fileBuffer appends frames to a file, each prefixed with its length.
+// Popped frames are marked, so a restarted process resumes the others
+type fileBuffer struct {
+	file   *os.File
+	head   int64 // offset of the first frame
+	tail   int64 // end of the last frame
+	frames int
+}
+
+func newFileBuffer(name string) (*fileBuffer, error) {
+	file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	b := &fileBuffer{file: file}
+	if err := b.resume(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+// resume finds the frames not popped, and truncates a partial frame a
+// crash left at the end
+func (b *fileBuffer) resume() error {
+	stat, err := b.file.Stat()
+	if err != nil {
+		return err
+	}
+	popped := true
+	var header [4]byte
+	for b.tail+4 <= stat.Size() {
+		if _, err := b.file.ReadAt(header[:], b.tail); err != nil {
+			return err
+		}
+		length := binary.BigEndian.Uint32(header[:])
+		end := b.tail + 4 + int64(length&^fileBufferPopped)
+		if end > stat.Size() || !popped && length&fileBufferPopped != 0 {
+			break
+		}
+		if popped = length&fileBufferPopped != 0; popped {
+			b.head = end
+		} else {
+			b.frames++
+		}
+		b.tail = end
+	}
+	if b.frames == 0 {
+		b.head, b.tail = 0, 0
+	}
+	if b.tail < stat.Size() {
+		return b.file.Truncate(b.tail)
+	}
+	return nil
+}
+
+func (b *fileBuffer) push(frame []byte) error {
+	if len(frame) >= fileBufferPopped {
+		return ErrBufferFull
+	}
+	record := make([]byte, 4, 4+len(frame))
+	binary.BigEndian.PutUint32(record, uint32(len(frame)))
+	record = append(record, frame...)
+	if _, err := b.file.WriteAt(record, b.tail); err != nil {
+		return err
+	}
+	b.tail += int64(len(record))
+	b.frames++
+	return nil
+}
+
+func (b *fileBuffer) peek() ([]byte, error) {
+	var header [4]byte
+	_, err := b.file.ReadAt(header[:], b.head)
+	if err == nil {
+		frame := make([]byte, binary.BigEndian.Uint32(header[:]))
+		if _, err = b.file.ReadAt(frame, b.head+4); err == nil {
+			return frame, nil
+		}
+	}
+	if err != io.EOF {
+		return nil, err
+	}
+
+	// the file was truncated, the frames from head are lost
+	b.head, b.tail, b.frames = 0, 0, 0
+	b.file.Truncate(0)
+	return nil, errFileBufferCorrupt
+}
+
+func (b *fileBuffer) pop() {
+	if b.frames == 0 {
+		return
+	}
+	var header [4]byte
+	if _, err := b.file.ReadAt(header[:], b.head); err != nil {
+		b.head, b.tail, b.frames = 0, 0, 0
+	} else {
+		length := binary.BigEndian.Uint32(header[:])
+		binary.BigEndian.PutUint32(header[:], length|fileBufferPopped)
+		b.file.WriteAt(header[:], b.head)
+		b.head += 4 + int64(length)
+		b.frames--
+	}
+	if b.frames == 0 {
+		// reuse the file from start once drained
+		b.head, b.tail = 0, 0
+		b.file.Truncate(0)
+	} else if b.head >= fileBufferCompact && b.head >= b.tail-b.head {
+		// the queue may never drain under steady load
+		b.compact()
+	}
+}
+
+// compact moves the frames to the start of the file, the frames are not
+// longer than the space before them, so they never overlap while moved
+func (b *fileBuffer) compact() {
+	size := b.tail - b.head
+	_, err := io.Copy(io.NewOffsetWriter(b.file, 0), io.NewSectionReader(b.file, b.head, size))
+	if err == nil {
+		err = b.file.Truncate(size)
+	}
+	if err == nil {
+		b.head, b.tail = 0, size
+	}
+}
+
+func (b *fileBuffer) size() int64 {
+	// lengths are stored, but only frame bytes count to MaxBuffered
+	return b.tail - b.head - 4*int64(b.frames)
+}
+
+func (b *fileBuffer) close() error {
+	return b.file.Close()
+}
@@ -0,0 +1,304 @@
+package writer_test
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fun-think/gologger/writer"
+)
+
+// flakyServer receives newline framed lines, dropping the first connections
+// after a random number of lines
+type flakyServer struct {
+	ln    net.Listener
+	mutex sync.Mutex
+	lines []string
+}
+
+func newFlakyServer(t *testing.T, disconnects int) *flakyServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &flakyServer{ln: ln}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for i := 0; ; i++ {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			limit := -1
+			if i < disconnects {
+				limit = 1 + rand.Intn(20)
+			}
+			go s.serve(conn, limit)
+		}
+	}()
+	return s
+}
+
+func (s *flakyServer) serve(conn net.Conn, limit int) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for n := 0; limit < 0 || n < limit; n++ {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		s.mutex.Lock()
+		s.lines = append(s.lines, line)
+		s.mutex.Unlock()
+	}
+}
+
+func (s *flakyServer) received() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string(nil), s.lines...)
+}
+
+func TestNetWriterReconnect(t *testing.T) {
+	for _, buffer := range []string{"memory", "file"} {
+		t.Run(buffer, func(t *testing.T) {
+			server := newFlakyServer(t, 5)
+			w := &writer.NetWriter{
+				Network:    "tcp",
+				Addr:       server.ln.Addr().String(),
+				MinBackoff: time.Millisecond,
+				MaxBackoff: 10 * time.Millisecond,
+			}
+			if buffer == "file" {
+				w.BufferFile = filepath.Join(t.TempDir(), "buffer")
+			}
+
+			const total = 200
+			for i := 0; i < total; i++ {
+				fmt.Fprintf(w, "line %d\n", i)
+				if i%10 == 0 {
+					time.Sleep(time.Millisecond)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			// lines in flight when the server disconnects may be lost,
+			// but every received line must be intact and in order
+			var lines []string
+			for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(time.Millisecond) {
+				lines = server.received()
+				if len(lines) > 0 && lines[len(lines)-1] == fmt.Sprintf("line %d\n", total-1) {
+					break
+				}
+			}
+			last := -1
+			for _, line := range lines {
+				n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(line, "line "), "\n"))
+				if err != nil || n < last {
+					t.Fatalf("unexpected line %q after %d", line, last)
+				}
+				last = n
+			}
+			if last != total-1 {
+				t.Errorf("last line %d not flushed by Close", total-1)
+			}
+		})
+	}
+}
+
+func TestNetWriterLengthPrefix(t *testing.T) {
+	ln, err := net.Listen("unix", filepath.Join(t.TempDir(), "collector.sock"))
+	if err != nil {
+		t.Skip(err)
+	}
+	defer ln.Close()
+
+	w := &writer.NetWriter{Network: "unix", Addr: ln.Addr().String(), Framing: writer.LengthPrefixFraming}
+	w.Write([]byte("hello\n"))
+	w.Write([]byte("world"))
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	for _, want := range []string{"hello\n", "world"} {
+		var size uint32
+		if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+			t.Fatal(err)
+		}
+		msg := make([]byte, size)
+		if _, err := io.ReadFull(conn, msg); err != nil {
+			t.Fatal(err)
+		}
+		if string(msg) != want {
+			t.Errorf("got %q, want %q", msg, want)
+		}
+	}
+	w.Close()
+}
+
+func TestNetWriterDrop(t *testing.T) {
+	// nothing listens, so lines stay buffered
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := ln.Addr().String()
+	ln.Close()
+
+	for _, policy := range []writer.DropPolicy{writer.DropNewest, writer.DropOldest} {
+		w := &writer.NetWriter{Network: "tcp", Addr: addr, MaxBuffered: 20, DropPolicy: policy, FlushTimeout: 10 * time.Millisecond}
+		var errs int
+		for i := 0; i < 5; i++ {
+			if _, err := w.Write([]byte("12345678\n")); err != nil {
+				errs++
+			}
+		}
+		if w.Buffered() != 18 || w.Dropped() != 3 {
+			t.Errorf("policy %d: buffered %d, dropped %d", policy, w.Buffered(), w.Dropped())
+		}
+		if (policy == writer.DropNewest) != (errs == 3) {
+			t.Errorf("policy %d: %d write errors", policy, errs)
+		}
+		if err := w.Close(); err == nil {
+			t.Errorf("policy %d: Close should report undelivered lines", policy)
+		}
+	}
+}
+
+func TestNetWriterCloseDuringBackoff(t *testing.T) {
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := ln.Addr().String()
+	ln.Close()
+
+	w := &writer.NetWriter{Network: "tcp", Addr: addr, MinBackoff: 3 * time.Second, FlushTimeout: 100 * time.Millisecond}
+	w.Write([]byte("line\n"))
+	time.Sleep(50 * time.Millisecond)
+
+	start := time.Now()
+	w.Close()
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Close took %v", d)
+	}
+}
+
+func TestNetWriterBufferFileSize(t *testing.T) {
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := ln.Addr().String()
+	ln.Close()
+
+	// lines are dropped from the head, so the queue never drains
+	name := filepath.Join(t.TempDir(), "buffer")
+	w := &writer.NetWriter{Network: "tcp", Addr: addr, BufferFile: name, MaxBuffered: 64 << 10, DropPolicy: writer.DropOldest, FlushTimeout: time.Millisecond}
+	defer w.Close()
+	line := []byte(strings.Repeat("x", 1023) + "\n")
+	for i := 0; i < 10000; i++ {
+		w.Write(line)
+	}
+
+	stat, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Size() > 4<<20 {
+		t.Errorf("buffer file has %d bytes for %d buffered", stat.Size(), w.Buffered())
+	}
+}
+
+func TestNetWriterReopen(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	conns := make(chan string)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				line, _ := bufio.NewReader(conn).ReadString('\n')
+				conns <- line
+			}()
+		}
+	}()
+
+	w := &writer.NetWriter{Network: "tcp", Addr: ln.Addr().String()}
+	defer w.Close()
+	w.Write([]byte("first\n"))
+	if line := <-conns; line != "first\n" {
+		t.Fatalf("got %q", line)
+	}
+
+	// the second line is read from a new connection
+	w.Reopen()
+	w.Write([]byte("second\n"))
+	select {
+	case line := <-conns:
+		if line != "second\n" {
+			t.Errorf("got %q", line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("no new connection")
+	}
+}
+
+func TestNetWriterBufferFileResume(t *testing.T) {
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	unreachable := ln.Addr().String()
+	ln.Close()
+
+	// a process exits before the collector is reachable
+	name := filepath.Join(t.TempDir(), "buffer")
+	w := &writer.NetWriter{Network: "tcp", Addr: unreachable, BufferFile: name, FlushTimeout: time.Millisecond}
+	fmt.Fprintln(w, "line 0")
+	fmt.Fprintln(w, "line 1")
+	w.Close()
+
+	// then one crashes while buffering a frame of 16 bytes
+	file, _ := os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0)
+	file.Write([]byte{0, 0, 0, 16, 'l', 'i'})
+	file.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	w = &writer.NetWriter{Network: "tcp", Addr: ln.Addr().String(), BufferFile: name}
+	fmt.Fprintln(w, "line 2")
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+	for i := 0; i < 3; i++ {
+		if line, _ := r.ReadString('\n'); line != fmt.Sprintf("line %d\n", i) {
+			t.Errorf("got %q, want line %d", line, i)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Error(err)
+	}
+
+	// delivered lines are not sent again
+	if stat, err := os.Stat(name); err != nil || stat.Size() != 0 {
+		t.Errorf("buffer file left %v, %v", stat, err)
+	}
+}
@@ -7,43 +7,100 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
 // NewFileWriter create new log for every process
 type NewFileWriter struct {
-	Name     string
+	Name     string // symlink to the current file
 	MaxCount int
+	FileOptions
 
-	file *os.File
+	file     *os.File
+	syncErr  error // of closed files, returned by Sync
+	reopen   atomic.Bool
+	counters counters
+}
+
+// Stats returns a snapshot of counters
+func (w *NewFileWriter) Stats() Stats {
+	return w.counters.snapshot()
 }
 
 // Write implements io.Writer
 func (w *NewFileWriter) Write(p []byte) (n int, err error) {
+	if _, _, err := w.rotate(); err != nil {
+		return 0, err
+	}
+	return w.writeFile(p)
+}
+
+// Sync commits the current file to stable storage, and returns the error
+// of committing the files closed since the last Sync
+func (w *NewFileWriter) Sync() error {
+	return syncFile(w.file, &w.syncErr)
+}
+
+// Reopen makes the next write reopen the file, after it was moved by
+// external rotation like logrotate
+func (w *NewFileWriter) Reopen() error {
+	w.reopen.Store(true)
+	return nil
+}
+
+func (w *NewFileWriter) rotate() (name string, size int64, err error) {
 	if w.file == nil {
-		err := w.openFile()
-		if err != nil {
-			return 0, err
+		if err := w.openFile(); err != nil {
+			return "", 0, err
 		}
+		return w.file.Name(), 0, nil
+	}
+	if !w.reopen.Swap(false) {
+		return "", 0, nil
 	}
 
-	return w.file.Write(p)
+	// the file of this process keeps its name
+	name = w.file.Name()
+	closeFile(w.file, &w.syncErr)
+	w.file, err = w.FileOptions.openFile(name, os.O_RDWR|os.O_CREATE|os.O_APPEND)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := w.linkCurrent(path.Base(name), w.Name); err != nil {
+		return "", 0, err
+	}
+	stat, err := w.file.Stat()
+	if err != nil {
+		return "", 0, err
+	}
+	w.counters.currentSize.Store(stat.Size())
+	return name, stat.Size(), nil
+}
+
+func (w *NewFileWriter) writeFile(p []byte) (n int, err error) {
+	n, err = w.file.Write(p)
+	w.counters.currentSize.Add(int64(n))
+	return n, err
 }
 
 func (w *NewFileWriter) openFile() (err error) {
 	name := fmt.Sprintf("%s.%s.log", w.Name, time.Now().Format("20060102150405"))
+	if err := w.mkdirAll(path.Dir(name)); err != nil {
+		return err
+	}
 
-	// remove symbol link if exist
-	os.Remove(w.Name)
-
-	// create symbol
-	err = os.Symlink(path.Base(name), w.Name)
+	w.file, err = w.FileOptions.openFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL)
 	if err != nil {
 		return err
 	}
 
-	w.file, err = os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	err = w.linkCurrent(path.Base(name), w.Name)
 	if err != nil {
+		// created above, so no stray file is left at every retry
+		w.file.Close()
+		w.file = nil
+		os.Remove(name)
 		return err
 	}
 
@@ -74,11 +131,12 @@ func (w *NewFileWriter) cleanFiles() {
 
 	if len(matches) > w.MaxCount {
 		sort.Sort(sort.Reverse(sort.StringSlice(matches)))
-		fmt.Println(matches)
 
 		for _, f := range matches[w.MaxCount:] {
 			file := filepath.Join(dir, f)
-			os.Remove(file)
+			if os.Remove(file) == nil {
+				w.counters.filesDeleted.Add(1)
+			}
 		}
 	}
 }
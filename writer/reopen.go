@@ -0,0 +1,55 @@
+package writer
+
+import (
+	"os"
+	"os/signal"
+)
+
+// Reopener is implemented by writers which can reopen their file or
+// connection. File writers and NetWriter reopen at the next write, so Reopen
+// is safe to call while another goroutine writes
+type Reopener interface {
+	Reopen() error
+}
+
+// ReopenOnSignal calls w.Reopen whenever one of signals is received,
+// SIGUSR1 and SIGHUP by default on unix, until stop is called
+func ReopenOnSignal(w Reopener, signals ...os.Signal) (stop func()) {
+	if len(signals) == 0 {
+		signals = reopenSignals
+	}
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, signals...)
+
+	go func() {
+		for {
+			select {
+			case <-ch:
+				w.Reopen()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(ch)
+		close(done)
+	}
+}
+
+var (
+	_ Reopener = (*FileWriter)(nil)
+	_ Reopener = (*DailyFileWriter)(nil)
+	_ Reopener = (*NewFileWriter)(nil)
+	_ Reopener = (*SizeFileWriter)(nil)
+	_ Reopener = (*ChainWriter)(nil)
+	_ Reopener = (*EncryptWriter)(nil)
+	_ Reopener = (*FallbackWriter)(nil)
+	_ Reopener = (*RetryWriter)(nil)
+	_ Reopener = (*SyslogWriter)(nil)
+	_ Reopener = (*JournaldWriter)(nil)
+	_ Reopener = (*NetWriter)(nil)
+	_ Reopener = (*HTTPWriter)(nil)
+)
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package writer
+
+import "os"
+
+// reopenSignals are used by ReopenOnSignal by default, there are none
+// without SIGUSR1 and SIGHUP
+var reopenSignals []os.Signal
@@ -0,0 +1,100 @@
+//go:build unix
+
+package writer_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/fun-think/gologger/writer"
+)
+
+func TestFileWriterReopen(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	w := &writer.FileWriter{Name: name, CheckInterval: -1}
+	stop := writer.ReopenOnSignal(w)
+	defer stop()
+
+	fmt.Fprintln(w, "before")
+
+	// logrotate create mode: move, then signal
+	os.Rename(name, name+".1")
+	fmt.Fprintln(w, "moved")
+	syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+
+	// lines before the reopen still go to the moved file
+	written := 0
+	for ; written < 100 && fileContent(name) == ""; written++ {
+		fmt.Fprintln(w, "after")
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	old, current := fileContent(name+".1"), fileContent(name)
+	if !strings.HasPrefix(old, "before\nmoved\n") || current != "after\n" ||
+		strings.Count(old+current, "after\n") != written {
+		t.Errorf("rotated file has %q, new file has %q after %d writes", old, current, written)
+	}
+}
+
+func TestFileWriterDeleted(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	w := &writer.FileWriter{Name: name, CheckInterval: time.Nanosecond}
+
+	fmt.Fprintln(w, "first")
+	os.Remove(name)
+	time.Sleep(time.Millisecond)
+	fmt.Fprintln(w, "second")
+
+	if got := fileContent(name); got != "second\n" {
+		t.Errorf("got %q", got)
+	}
+	if w.Stats().Rotations != 1 {
+		t.Errorf("%d reopens", w.Stats().Rotations)
+	}
+}
+
+func TestDailyFileWriterReopen(t *testing.T) {
+	dir := t.TempDir()
+	w := &writer.DailyFileWriter{Name: dir}
+	name := filepath.Join(dir, time.Now().Format("20060102")+".log")
+
+	fmt.Fprintln(w, "first")
+	os.Rename(name, name+".1")
+	w.Reopen()
+	fmt.Fprintln(w, "second")
+
+	if got := fileContent(name); got != "second\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestFileWritersMoved(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		w    io.Writer
+		name string
+	}{
+		{&writer.DailyFileWriter{Name: dir, CheckInterval: time.Nanosecond}, filepath.Join(dir, time.Now().Format("20060102")+".log")},
+		{&writer.SizeFileWriter{Name: filepath.Join(dir, "app"), MaxSize: 1024, MaxCount: 2, CheckInterval: time.Nanosecond}, filepath.Join(dir, "app.0.log")},
+	} {
+		fmt.Fprintln(tc.w, "first")
+		os.Rename(tc.name, tc.name+".1")
+		time.Sleep(time.Millisecond)
+		fmt.Fprintln(tc.w, "second")
+
+		if got := fileContent(tc.name); got != "second\n" {
+			t.Errorf("%T: got %q", tc.w, got)
+		}
+	}
+}
+
+func fileContent(name string) string {
+	data, _ := os.ReadFile(name)
+	return string(data)
+}
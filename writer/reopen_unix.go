@@ -0,0 +1,11 @@
+//go:build unix
+
+package writer
+
+import (
+	"os"
+	"syscall"
+)
+
+// reopenSignals are used by ReopenOnSignal by default
+var reopenSignals = []os.Signal{syscall.SIGUSR1, syscall.SIGHUP}
@@ -0,0 +1,72 @@
+package writer
+
+import (
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"time"
+)
+
+// RetryWriter retries transient write errors with exponential backoff
+type RetryWriter struct {
+	Writer     io.Writer
+	MaxRetries int           // default is 3, negative disables retries
+	Backoff    time.Duration // delay before first retry, doubled each time, default is 10ms
+
+	// IsTransient decides whether to retry an error, default is IsTransient
+	IsTransient func(err error) bool
+}
+
+// Write implements io.Writer
+func (w *RetryWriter) Write(p []byte) (n int, err error) {
+	retries := w.MaxRetries
+	if retries == 0 {
+		retries = 3
+	} else if retries < 0 {
+		retries = 0
+	}
+	backoff := w.Backoff
+	if backoff <= 0 {
+		backoff = 10 * time.Millisecond
+	}
+	isTransient := w.IsTransient
+	if isTransient == nil {
+		isTransient = IsTransient
+	}
+
+	for i := 0; ; i++ {
+		var m int
+		m, err = w.Writer.Write(p[n:])
+		n += m
+		if err == nil || i >= retries || !isTransient(err) {
+			return n, err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// Reopen reopens Writer if it supports it
+func (w *RetryWriter) Reopen() error {
+	if r, ok := w.Writer.(Reopener); ok {
+		return r.Reopen()
+	}
+	return nil
+}
+
+// IsTransient reports whether err is likely to go away on retry
+func IsTransient(err error) bool {
+	if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR) ||
+		errors.Is(err, syscall.EBUSY) || errors.Is(err, syscall.ENOBUFS) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	var tempErr interface{ Temporary() bool }
+	return errors.As(err, &tempErr) && tempErr.Temporary()
+}
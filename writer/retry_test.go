@@ -0,0 +1,52 @@
+package writer_test
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/fun-think/gologger/writer"
+)
+
+// flakyWriter fails the first fails writes with err, recording when written
+type flakyWriter struct {
+	fails int
+	err   error
+	times []time.Time
+}
+
+func (w *flakyWriter) Write(p []byte) (int, error) {
+	w.times = append(w.times, time.Now())
+	if len(w.times) <= w.fails {
+		return 0, w.err
+	}
+	return len(p), nil
+}
+
+func TestRetryWriter(t *testing.T) {
+	for _, tc := range []struct {
+		retries, fails, writes int
+		err                    error
+		ok                     bool
+	}{
+		{0, 3, 4, syscall.EAGAIN, true},   // default is 3 retries
+		{0, 4, 4, syscall.EAGAIN, false},  // gives up after 3 retries
+		{1, 1, 2, syscall.EAGAIN, true},   // MaxRetries
+		{-1, 1, 1, syscall.EAGAIN, false}, // retries disabled
+		{0, 1, 1, syscall.ENOSPC, false},  // not transient
+	} {
+		out := &flakyWriter{fails: tc.fails, err: tc.err}
+		w := &writer.RetryWriter{Writer: out, MaxRetries: tc.retries, Backoff: 5 * time.Millisecond}
+		_, err := w.Write([]byte("line\n"))
+		if (err == nil) != tc.ok || len(out.times) != tc.writes {
+			t.Errorf("%+v: %d writes, %v", tc, len(out.times), err)
+		}
+
+		// the backoff doubles
+		for i := 1; i < len(out.times); i++ {
+			if d := out.times[i].Sub(out.times[i-1]); d < 5*time.Millisecond<<(i-1) {
+				t.Errorf("%+v: retry %d after %v", tc, i, d)
+			}
+		}
+	}
+}
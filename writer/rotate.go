@@ -0,0 +1,24 @@
+package writer
+
+import "io"
+
+// rotator is implemented by the file writers, and by wrappers of them, so a
+// wrapper can write a header or checkpoint at the start of every file
+type rotator interface {
+	io.Writer
+
+	// rotate opens the first or the next file if needed, and returns the
+	// name and size of the opened file, or "" if the current file is kept
+	rotate() (name string, size int64, err error)
+
+	// writeFile writes to the current file without rotating
+	writeFile(p []byte) (n int, err error)
+}
+
+var (
+	_ rotator = (*FileWriter)(nil)
+	_ rotator = (*DailyFileWriter)(nil)
+	_ rotator = (*NewFileWriter)(nil)
+	_ rotator = (*SizeFileWriter)(nil)
+	_ rotator = (*EncryptWriter)(nil)
+)
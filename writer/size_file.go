@@ -1,113 +1,296 @@
 package writer
 
 import (
+	"errors"
 	"fmt"
-	"math"
 	"os"
 	"path"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
-// SizeFileWriter create new log if log size exceed
+// ErrSizeFileConfig is returned by SizeFileWriter without MaxSize or MaxCount
+var ErrSizeFileConfig = errors.New("SizeFileWriter needs MaxSize and MaxCount greater than 0")
+
+// SizeFileWriter create new log if log size exceed. Files are Name.0.log to
+// Name.<MaxCount-1>.log used in turn, the index of the current one is kept
+// in the symlink Name, or in a hidden index file with NoSymlink
 type SizeFileWriter struct {
-	Name     string
+	Name     string // symlink to the current file
 	MaxSize  int64
 	MaxCount int
+	FileOptions
+
+	// Shared lets several processes write the same files. Rotation is
+	// serialized by flock on Name+".lock", a process reopens the linked file
+	// when another one rotated, and every Write is a single append, atomic
+	// for lines shorter than PIPE_BUF. It needs the symlink and MaxCount of at
+	// least 2, since rotation replaces the file other processes may still write
+	Shared bool
+
+	// CheckInterval is how often the current file is checked for being
+	// moved or deleted to reopen it, default is 1 second, negative disables
+	// checking. Shared checks at every write
+	CheckInterval time.Duration
+
+	file     *os.File
+	syncErr  error // of closed files, returned by Sync
+	check    moveCheck
+	index    int // of the current file
+	reopen   atomic.Bool
+	counters counters
+}
 
-	file        *os.File
-	currentSize int64
+// Stats returns a snapshot of counters
+func (w *SizeFileWriter) Stats() Stats {
+	return w.counters.snapshot()
 }
 
 // Write implements io.Writer
 func (w *SizeFileWriter) Write(p []byte) (n int, err error) {
+	if _, _, err := w.rotate(); err != nil {
+		return 0, err
+	}
+	return w.writeFile(p)
+}
+
+// Sync commits the current file to stable storage, and returns the error
+// of committing the files closed since the last Sync
+func (w *SizeFileWriter) Sync() error {
+	return syncFile(w.file, &w.syncErr)
+}
+
+// Reopen makes the next write reopen the linked file, after it was moved
+// by external rotation like logrotate
+func (w *SizeFileWriter) Reopen() error {
+	w.reopen.Store(true)
+	return nil
+}
+
+func (w *SizeFileWriter) rotate() (name string, size int64, err error) {
+	if w.MaxSize <= 0 || w.MaxCount <= 0 {
+		return "", 0, ErrSizeFileConfig
+	}
+	if w.Shared {
+		return w.rotateShared()
+	}
+
 	if w.file == nil {
 		err := w.openCurrentFile()
 		if err != nil {
-			return 0, err
+			return "", 0, err
 		}
-	} else if w.currentSize > w.MaxSize {
-		w.file.Close()
+	} else if w.reopen.Swap(false) || w.check.moved(w.file, w.CheckInterval) {
+		closeFile(w.file, &w.syncErr)
+		w.file = nil
+		err := w.openCurrentFile()
+		if err != nil {
+			return "", 0, err
+		}
+	} else if w.counters.currentSize.Load() > w.MaxSize {
+		closeFile(w.file, &w.syncErr)
 		err := w.openNextFile()
 		if err != nil {
-			return 0, err
+			return "", 0, err
 		}
+		w.counters.rotations.Add(1)
+	} else {
+		return "", 0, nil
 	}
 
-	w.currentSize += int64(len(p))
-
-	return w.file.Write(p)
+	return w.file.Name(), w.counters.currentSize.Load(), nil
 }
 
-func (w *SizeFileWriter) openCurrentFile() error {
-	name, err := os.Readlink(w.Name)
+// rotateShared reopens the linked file if another process rotated, and
+// rotates holding the lock so that only one process does
+func (w *SizeFileWriter) rotateShared() (name string, size int64, err error) {
+	if w.NoSymlink {
+		return "", 0, errors.New("SizeFileWriter.Shared needs the symlink")
+	}
+	if w.MaxCount < 2 {
+		return "", 0, errors.New("SizeFileWriter.Shared needs MaxCount of at least 2")
+	}
+	if w.file != nil && !w.reopen.Swap(false) {
+		current, err := w.file.Stat()
+		linked, lerr := os.Stat(w.Name)
+		if err == nil && lerr == nil && os.SameFile(current, linked) && current.Size() <= w.MaxSize {
+			w.counters.currentSize.Store(current.Size())
+			return "", 0, nil
+		}
+	}
+
+	if err := w.mkdirAll(path.Dir(w.Name)); err != nil {
+		return "", 0, err
+	}
+	unlock, err := lockFile(w.Name+".lock", w.FileOptions)
 	if err != nil {
-		name = w.getAvailableFileName()
+		return "", 0, err
+	}
+	defer unlock()
 
-		// create a symlink
-		err = os.Symlink(path.Base(name), w.Name)
-		if err != nil {
-			return err
+	// another process may have rotated while waiting for the lock
+	rotated := w.file != nil
+	if rotated {
+		closeFile(w.file, &w.syncErr)
+		w.file = nil
+	}
+	if err := w.openCurrentFile(); err != nil {
+		return "", 0, err
+	}
+	if w.counters.currentSize.Load() > w.MaxSize {
+		closeFile(w.file, &w.syncErr)
+		if err := w.openNextFile(); err != nil {
+			return "", 0, err
 		}
-	} else {
-		// convert to abs path
-		name = path.Join(path.Dir(w.Name), name)
+		rotated = true
+	}
+	if rotated {
+		w.counters.rotations.Add(1)
 	}
 
-	w.file, err = os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	return w.file.Name(), w.counters.currentSize.Load(), nil
+}
+
+func (w *SizeFileWriter) writeFile(p []byte) (n int, err error) {
+	n, err = w.file.Write(p)
+	w.counters.currentSize.Add(int64(n))
+	return n, err
+}
+
+func (w *SizeFileWriter) openCurrentFile() error {
+	if err := w.mkdirAll(path.Dir(w.Name)); err != nil {
+		return err
+	}
+
+	index := w.currentIndex()
+	name := w.fileName(index)
+
+	file, err := w.openFile(name, os.O_RDWR|os.O_CREATE|os.O_APPEND)
+	if err != nil {
+		return err
+	}
+
+	err = w.setCurrent(index)
 	if err != nil {
+		file.Close()
 		return err
 	}
+	w.file = file
+	w.index = index
 
 	stat, err := w.file.Stat()
 	if err != nil {
 		return err
 	}
-	w.currentSize = stat.Size()
+	w.counters.currentSize.Store(stat.Size())
 
 	return nil
 }
 
 func (w *SizeFileWriter) openNextFile() (err error) {
-	name := w.getAvailableFileName()
-
-	// remove symbol link
-	err = os.Remove(w.Name)
-	if err != nil {
-		return err
+	index := w.index + 1
+	if index >= w.MaxCount {
+		index = 0
 	}
+	name := w.fileName(index)
 
-	// create symbol
-	err = os.Symlink(path.Base(name), w.Name)
-	if err != nil {
-		return err
+	flag := os.O_RDWR | os.O_CREATE | os.O_APPEND | os.O_TRUNC
+	if w.Shared {
+		// a new inode, so processes still writing the old file notice
+		os.Remove(name)
+		flag = os.O_RDWR | os.O_CREATE | os.O_APPEND | os.O_EXCL
 	}
-
-	w.file, err = os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	w.file, err = w.openFile(name, flag)
 	if err != nil {
 		return err
 	}
+	w.index = index
+	w.counters.currentSize.Store(0)
 
-	w.currentSize = 0
+	// the link keeps pointing to the previous file until replaced, after a
+	// crash in between the previous file is resumed and rotated again
+	return w.setCurrent(index)
+}
 
-	return nil
+// fileName returns the name of the file with index
+func (w *SizeFileWriter) fileName(index int) string {
+	return fmt.Sprintf("%s.%d.log", w.Name, index)
+}
+
+// indexFile persists the index of the current file with NoSymlink
+func (w *SizeFileWriter) indexFile() string {
+	return path.Join(path.Dir(w.Name), "."+path.Base(w.Name)+".index")
 }
 
-// get available file or oldest file
-func (w *SizeFileWriter) getAvailableFileName() string {
-	var oldestTime int64 = math.MaxInt64
-	var oldestName string
+// currentIndex returns the index of the current file, read from the
+// symlink or the index file, so a restarted process resumes it. An index
+// out of the rotation set, e.g. after MaxCount was lowered, is reset to 0
+func (w *SizeFileWriter) currentIndex() int {
+	if index, ok := w.persistedIndex(); ok {
+		if index >= w.MaxCount {
+			return 0
+		}
+		return index
+	}
 
+	// without state continue the newest file, the lowest index if equal
+	index := 0
+	var newest time.Time
 	for i := 0; i < w.MaxCount; i++ {
-		name := fmt.Sprintf("%s.%d.log", w.Name, i)
-		stat, err := os.Stat(name)
-		if err != nil {
-			return name
+		if stat, err := os.Stat(w.fileName(i)); err == nil && stat.ModTime().After(newest) {
+			index, newest = i, stat.ModTime()
 		}
+	}
+	return index
+}
 
-		if fTime := stat.ModTime().Unix(); fTime < oldestTime {
-			oldestTime = fTime
-			oldestName = name
+// persistedIndex reads the index from the symlink or the index file
+func (w *SizeFileWriter) persistedIndex() (index int, ok bool) {
+	var i string
+	if !w.NoSymlink {
+		target, err := os.Readlink(w.Name)
+		if err != nil {
+			return 0, false
 		}
+		if i, ok = strings.CutPrefix(path.Base(target), path.Base(w.Name)+"."); !ok {
+			return 0, false
+		}
+		i = strings.TrimSuffix(i, ".log")
+	} else {
+		data, err := os.ReadFile(w.indexFile())
+		if err != nil {
+			return 0, false
+		}
+		i = strings.TrimSpace(string(data))
 	}
+	index, err := strconv.Atoi(i)
+	return index, err == nil && index >= 0
+}
 
-	return oldestName
+// setCurrent persists index as the current file
+func (w *SizeFileWriter) setCurrent(index int) error {
+	if !w.NoSymlink {
+		return w.linkCurrent(path.Base(w.fileName(index)), w.Name)
+	}
+
+	// replaced atomically, so it is never empty
+	name := w.indexFile()
+	tmp := name + "." + strconv.Itoa(os.Getpid()) + ".tmp"
+	file, err := w.openFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return err
+	}
+	_, err = file.WriteString(strconv.Itoa(index) + "\n")
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, name)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
 }
@@ -0,0 +1,122 @@
+//go:build linux || darwin
+
+package writer_test
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/fun-think/gologger/writer"
+)
+
+func TestSizeFileWriterShared(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app")
+	const procs, lines, maxSize = 4, 500, 2000
+
+	for _, w := range []*writer.SizeFileWriter{
+		{Name: name, MaxSize: maxSize, MaxCount: 1, Shared: true},
+		{Name: name, MaxSize: maxSize, MaxCount: 2, Shared: true, FileOptions: writer.FileOptions{NoSymlink: true}},
+	} {
+		if _, err := fmt.Fprintln(w, "line"); err == nil {
+			t.Errorf("MaxCount %d NoSymlink %v: no error", w.MaxCount, w.NoSymlink)
+		}
+	}
+
+	// every writer has its own files and locks like a separate process
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for p := 0; p < procs; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			<-start
+			w := &writer.SizeFileWriter{Name: name, MaxSize: maxSize, MaxCount: 1000, Shared: true}
+			for i := 0; i < lines; i++ {
+				if _, err := fmt.Fprintf(w, "process %d line %03d\n", p, i); err != nil {
+					t.Error(err)
+					return
+				}
+				runtime.Gosched()
+			}
+		}(p)
+	}
+	close(start)
+	wg.Wait()
+
+	seen := map[string]bool{}
+	files, _ := filepath.Glob(name + ".*.log")
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stat, _ := f.Stat()
+		if stat.Size() > maxSize+procs*32 {
+			t.Errorf("%s has %d bytes", file, stat.Size())
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var p, i int
+			if _, err := fmt.Sscanf(scanner.Text(), "process %d line %03d", &p, &i); err != nil || seen[scanner.Text()] {
+				t.Errorf("%s: bad or duplicate line %q", file, scanner.Text())
+			}
+			seen[scanner.Text()] = true
+		}
+		f.Close()
+	}
+	if len(seen) != procs*lines {
+		t.Errorf("%d of %d lines in %d files", len(seen), procs*lines, len(files))
+	}
+}
+
+func TestSizeFileWriterRotation(t *testing.T) {
+	for _, w := range []*writer.SizeFileWriter{{MaxSize: 10}, {MaxCount: 3}} {
+		w.Name = filepath.Join(t.TempDir(), "app")
+		if _, err := fmt.Fprintln(w, "line"); !errors.Is(err, writer.ErrSizeFileConfig) {
+			t.Errorf("MaxSize %d MaxCount %d: %v", w.MaxSize, w.MaxCount, err)
+		}
+	}
+
+	for _, noSymlink := range []bool{false, true} {
+		name := filepath.Join(t.TempDir(), "app")
+		newWriter := func() *writer.SizeFileWriter {
+			return &writer.SizeFileWriter{Name: name, MaxSize: 10, MaxCount: 3, FileOptions: writer.FileOptions{NoSymlink: noSymlink}}
+		}
+
+		// files are used in turn, even when written within the same second
+		w := newWriter()
+		for i := 0; i < 7; i++ {
+			fmt.Fprintf(w, "line %d of the file\n", i)
+		}
+		for i, want := range []string{"line 6 of the file\n", "line 4 of the file\n", "line 5 of the file\n"} {
+			if got := fileContent(fmt.Sprintf("%s.%d.log", name, i)); got != want {
+				t.Errorf("NoSymlink %v: file %d has %q, want %q", noSymlink, i, got, want)
+			}
+		}
+
+		// a restarted process resumes the current file, then rotates to the next one
+		w = newWriter()
+		fmt.Fprintln(w, "resumed")
+		fmt.Fprintln(w, "rotated")
+		if got := fileContent(name + ".0.log"); got != "line 6 of the file\nresumed\n" {
+			t.Errorf("NoSymlink %v: resumed file has %q", noSymlink, got)
+		}
+		if got := fileContent(name + ".1.log"); got != "rotated\n" {
+			t.Errorf("NoSymlink %v: next file has %q", noSymlink, got)
+		}
+
+		// the current file is out of the lowered rotation set
+		w = newWriter()
+		w.MaxCount = 1
+		fmt.Fprintln(w, "lowered")
+		if got := fileContent(name + ".0.log"); got != "line 6 of the file\nresumed\nlowered\n" {
+			t.Errorf("NoSymlink %v: first file has %q", noSymlink, got)
+		}
+	}
+}
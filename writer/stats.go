@@ -0,0 +1,25 @@
+package writer
+
+import "sync/atomic"
+
+// Stats is a snapshot of writer counters
+type Stats struct {
+	Rotations    uint64 // files switched to after the first one
+	FilesDeleted uint64 // old files removed by MaxCount
+	CurrentSize  int64  // size of the current file
+}
+
+// counters are updated lock-free so Stats can be called from any goroutine
+type counters struct {
+	rotations    atomic.Uint64
+	filesDeleted atomic.Uint64
+	currentSize  atomic.Int64
+}
+
+func (c *counters) snapshot() Stats {
+	return Stats{
+		Rotations:    c.rotations.Load(),
+		FilesDeleted: c.filesDeleted.Load(),
+		CurrentSize:  c.currentSize.Load(),
+	}
+}
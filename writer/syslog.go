@@ -0,0 +1,275 @@
+package writer
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fun-think/gologger"
+)
+
+// SyslogProtocol is the message layout of SyslogWriter
+type SyslogProtocol int
+
+// These are the supported syslog protocols
+const (
+	RFC3164 SyslogProtocol = iota
+	RFC5424
+)
+
+// Syslog facilities
+const (
+	LOG_KERN = iota
+	LOG_USER
+	LOG_MAIL
+	LOG_DAEMON
+	LOG_AUTH
+	LOG_SYSLOG
+	LOG_LPR
+	LOG_NEWS
+	LOG_UUCP
+	LOG_CRON
+	LOG_AUTHPRIV
+	LOG_FTP
+	_
+	_
+	_
+	_
+	LOG_LOCAL0
+	LOG_LOCAL1
+	LOG_LOCAL2
+	LOG_LOCAL3
+	LOG_LOCAL4
+	LOG_LOCAL5
+	LOG_LOCAL6
+	LOG_LOCAL7
+)
+
+// SyslogSeverity returns the syslog severity of level
+func SyslogSeverity(level gologger.Level) int {
+	switch level {
+	case gologger.FATAL:
+		return 1 // alert
+	case gologger.PANIC:
+		return 2 // crit
+	case gologger.ERROR:
+		return 3 // err
+	case gologger.WARN:
+		return 4 // warning
+	case gologger.DEBUG:
+		return 7 // debug
+	default:
+		return 6 // info
+	}
+}
+
+// syslogSockets are tried in order when Network is empty
+var syslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// SyslogWriter sends log lines to a syslog server
+type SyslogWriter struct {
+	Network  string // udp, tcp, unix or unixgram, empty for the local syslog socket
+	Addr     string
+	Protocol SyslogProtocol
+	Facility int    // default is LOG_USER, LOG_KERN can not be used
+	Tag      string // default is the program name
+	Hostname string // default is os.Hostname()
+
+	// OctetCounting frames messages on stream connections with their
+	// length (RFC 6587), instead of terminating them with a newline
+	OctetCounting bool
+
+	// SDID is the structured-data id of entry fields in RFC5424, default is "fields@32473"
+	SDID string
+
+	mutex  sync.Mutex
+	conn   net.Conn
+	local  bool // connected to the local syslog socket
+	stream bool // messages need framing
+}
+
+// Write implements io.Writer, lines are sent with severity info
+func (w *SyslogWriter) Write(p []byte) (n int, err error) {
+	return w.send(6, time.Now(), nil, p)
+}
+
+// WriteEntry implements gologger.EntryWriter
+func (w *SyslogWriter) WriteEntry(entry *gologger.Entry, p []byte) (n int, err error) {
+	return w.send(SyslogSeverity(entry.Level), entry.Time, entry.Fields, p)
+}
+
+// Close closes the connection
+func (w *SyslogWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// Reopen closes the connection, the next write connects again
+func (w *SyslogWriter) Reopen() error {
+	return w.Close()
+}
+
+func (w *SyslogWriter) send(severity int, t time.Time, fields gologger.Fields, p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	msg := strings.TrimRight(string(p), "\n")
+
+	var err error
+	for retry := 0; retry < 2; retry++ {
+		if w.conn == nil {
+			if err = w.connect(); err != nil {
+				continue
+			}
+		}
+		if _, err = w.conn.Write(w.frame(severity, t, fields, msg)); err == nil {
+			return len(p), nil
+		}
+		// reconnect once, the server may have restarted
+		w.conn.Close()
+		w.conn = nil
+	}
+	return 0, err
+}
+
+func (w *SyslogWriter) connect() (err error) {
+	if w.Network != "" {
+		w.conn, err = net.Dial(w.Network, w.Addr)
+		if err != nil {
+			return err
+		}
+		w.local = w.Network == "unix" || w.Network == "unixgram"
+		w.stream = w.Network == "unix" || strings.HasPrefix(w.Network, "tcp")
+		return nil
+	}
+
+	addrs := syslogSockets
+	if w.Addr != "" {
+		addrs = []string{w.Addr}
+	}
+	for _, addr := range addrs {
+		for _, network := range []string{"unixgram", "unix"} {
+			w.conn, err = net.Dial(network, addr)
+			if err == nil {
+				w.local = true
+				w.stream = network == "unix"
+				return nil
+			}
+		}
+	}
+	return errors.New("unix syslog delivery error")
+}
+
+// rfc5424Time is the TIMESTAMP of RFC5424, which allows at most 6 digits
+// of fractional seconds
+const rfc5424Time = "2006-01-02T15:04:05.000000Z07:00"
+
+// frame returns msg formatted as syslog packet, framed for stream connections
+func (w *SyslogWriter) frame(severity int, t time.Time, fields gologger.Fields, msg string) []byte {
+	facility := w.Facility
+	if facility == 0 {
+		facility = LOG_USER
+	}
+	tag := w.Tag
+	if tag == "" {
+		tag = filepath.Base(os.Args[0])
+	}
+	hostname := w.Hostname
+	if hostname == "" {
+		hostname, _ = os.Hostname()
+	}
+	pri := facility*8 + severity
+
+	var packet string
+	if w.Protocol == RFC5424 {
+		packet = fmt.Sprintf("<%d>1 %s %s %s %d - %s %s",
+			pri, t.Format(rfc5424Time), headerValue(hostname, 255), headerValue(tag, 48), os.Getpid(),
+			w.structuredData(fields), msg)
+	} else if w.local {
+		packet = fmt.Sprintf("<%d>%s %s[%d]: %s", pri, t.Format(time.Stamp), tag, os.Getpid(), msg)
+	} else {
+		packet = fmt.Sprintf("<%d>%s %s %s[%d]: %s", pri, t.Format(time.Stamp), hostname, tag, os.Getpid(), msg)
+	}
+
+	if !w.stream {
+		return []byte(packet)
+	}
+	if w.OctetCounting {
+		return []byte(strconv.Itoa(len(packet)) + " " + packet)
+	}
+	return []byte(packet + "\n")
+}
+
+// structuredData returns fields as a RFC5424 SD-ELEMENT
+func (w *SyslogWriter) structuredData(fields gologger.Fields) string {
+	if len(fields) == 0 {
+		return "-"
+	}
+	sdid := w.SDID
+	if sdid == "" {
+		sdid = "fields@32473"
+	}
+
+	var sb strings.Builder
+	sb.WriteByte('[')
+	sb.WriteString(sdid)
+	for _, k := range fields.Keys() {
+		sb.WriteByte(' ')
+		sb.WriteString(sdName(k))
+		sb.WriteString(`="`)
+		sb.WriteString(sdEscaper.Replace(fmt.Sprint(fields[k])))
+		sb.WriteByte('"')
+	}
+	sb.WriteByte(']')
+	return sb.String()
+}
+
+var sdEscaper = strings.NewReplacer(`"`, `\"`, `\`, `\\`, `]`, `\]`)
+
+// sdName returns k usable as PARAM-NAME, at most 32 printable chars without '= ]"'
+func sdName(k string) string {
+	name := []byte(k)
+	for i, c := range name {
+		if c <= ' ' || c >= 127 || c == '=' || c == ']' || c == '"' {
+			name[i] = '_'
+		}
+	}
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	if len(name) == 0 {
+		return "_"
+	}
+	return string(name)
+}
+
+// headerValue returns s as RFC5424 header field of at most max printable
+// US-ASCII chars, "-" if empty
+func headerValue(s string, max int) string {
+	if s == "" {
+		return "-"
+	}
+	b := []byte(s)
+	for i, c := range b {
+		if c <= ' ' || c >= 127 {
+			b[i] = '_'
+		}
+	}
+	if len(b) > max {
+		b = b[:max]
+	}
+	return string(b)
+}
@@ -0,0 +1,169 @@
+package writer_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fun-think/gologger"
+	"github.com/fun-think/gologger/writer"
+)
+
+// msgFormat outputs the message only
+type msgFormat struct{}
+
+func (msgFormat) Format(entry *gologger.Entry) []byte {
+	return []byte(entry.Message + "\n")
+}
+
+func TestSyslogUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	w := &writer.SyslogWriter{Network: "udp", Addr: conn.LocalAddr().String(), Tag: "app", Hostname: "web-1"}
+	defer w.Close()
+	logger := &gologger.Logger{Level: gologger.INFO, Format: msgFormat{}, Output: w}
+	logger.Error("boom")
+
+	buf := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	re := regexp.MustCompile(`^<11>\w{3} [ \d]\d \d\d:\d\d:\d\d web-1 app\[` + strconv.Itoa(os.Getpid()) + `\]: boom$`)
+	if !re.Match(buf[:n]) {
+		t.Errorf("unexpected packet %q", buf[:n])
+	}
+}
+
+func TestSyslogTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	w := &writer.SyslogWriter{
+		Network:       "tcp",
+		Addr:          ln.Addr().String(),
+		Protocol:      writer.RFC5424,
+		Facility:      writer.LOG_LOCAL0,
+		Tag:           strings.Repeat("a", 50),
+		Hostname:      "web 1",
+		OctetCounting: true,
+	}
+	defer w.Close()
+	logger := &gologger.Logger{Level: gologger.INFO, Format: msgFormat{}, Output: w}
+
+	logger.WithFields(gologger.Fields{"user": `a"b]`, "n": 1}).Warn("first")
+	logger.Info("second")
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+
+	pid := strconv.Itoa(os.Getpid())
+	timestamp := `\d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{6}(Z|[+-]\d\d:\d\d)`
+	for _, want := range []string{
+		`<132>1 ` + timestamp + ` web_1 a{48} ` + pid + ` - \[fields@32473 n="1" user="a\\"b\\]"\] first`,
+		`<134>1 ` + timestamp + ` web_1 a{48} ` + pid + ` - - second`,
+	} {
+		var size int
+		if _, err := fmt.Fscanf(r, "%d ", &size); err != nil {
+			t.Fatal(err)
+		}
+		msg := make([]byte, size)
+		if _, err := io.ReadFull(r, msg); err != nil {
+			t.Fatal(err)
+		}
+		if !regexp.MustCompile("^" + want + "$").Match(msg) {
+			t.Errorf("got %q, want %s", msg, want)
+		}
+	}
+}
+
+func TestSyslogTCPReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	w := &writer.SyslogWriter{Network: "tcp", Addr: ln.Addr().String(), Tag: "app"}
+	defer w.Close()
+
+	w.Write([]byte("first\n"))
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	lines := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		lines <- line
+	}()
+
+	deadline := time.After(5 * time.Second)
+	for {
+		w.Write([]byte("again\n"))
+		select {
+		case line := <-lines:
+			if !strings.HasSuffix(line, ": again\n") {
+				t.Errorf("unexpected line %q", line)
+			}
+			return
+		case <-deadline:
+			t.Fatal("writer did not reconnect")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestSyslogUnixgram(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "log.sock")
+	conn, err := net.ListenPacket("unixgram", name)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer conn.Close()
+
+	w := &writer.SyslogWriter{Addr: name, Tag: "app", Hostname: "web-1"}
+	defer w.Close()
+	logger := &gologger.Logger{Level: gologger.DEBUG, Format: msgFormat{}, Output: w}
+	logger.Debug("local")
+
+	buf := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// local messages have no hostname
+	re := regexp.MustCompile(`^<15>\w{3} [ \d]\d \d\d:\d\d:\d\d app\[\d+\]: local$`)
+	if !re.Match(buf[:n]) {
+		t.Errorf("unexpected packet %q", buf[:n])
+	}
+}